@@ -0,0 +1,148 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// benchHosts is the number of entries in the generated hosts file, which is
+// about the size of the larger public blocklists
+const benchHosts = 100000
+
+func benchContent(count int) string {
+	var b strings.Builder
+	b.WriteString("127.0.0.1 localhost\n::1 localhost ip6-localhost\n\n")
+	for i := 0; i < count; i++ {
+		if i%100 == 0 {
+			fmt.Fprintf(&b, "# group %d\n", i/100)
+		}
+		fmt.Fprintf(&b, "10.%d.%d.%d host%d.example.com host%d\n", i>>16&255, i>>8&255, i&255, i, i)
+	}
+	return b.String()
+}
+
+func benchHostfile(b *testing.B) (eh *Hostfile) {
+	b.Helper()
+	var err error
+	if eh, err = Parse(strings.NewReader(benchContent(benchHosts))); err != nil {
+		b.Fatal(err)
+	}
+	return
+}
+
+func BenchmarkParse(b *testing.B) {
+	content := benchContent(benchHosts)
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(strings.NewReader(content)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIndex(b *testing.B) {
+	eh := benchHostfile(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		eh.clearIndex()
+		eh.HostsForDomain("host1.example.com")
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	eh := benchHostfile(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		eh.Validate()
+	}
+}
+
+func BenchmarkRemoveHosts(b *testing.B) {
+	eh := benchHostfile(b)
+	hosts := eh.Hosts()
+	var removing []*Host
+	for i := 0; i < len(hosts); i += 10 {
+		removing = append(removing, hosts[i])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		positions := eh.RemoveHosts(removing...)
+		eh.InsertHosts(removing, positions)
+	}
+}
+
+// BenchmarkEditDomains is typing into the domains of an entry, each change
+// followed by the lookups and validation the editor does for the entry
+func BenchmarkEditDomains(b *testing.B) {
+	eh := benchHostfile(b)
+	eh.Validate()
+	host := eh.Hosts()[len(eh.Hosts())/2]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		host.SetDomains(fmt.Sprintf("edited%d.example.com", i%10))
+		eh.HostsForDomain("edited0.example.com")
+		eh.ValidateChanged()
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	content := benchContent(benchHosts)
+	path := filepath.Join(b.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		b.Fatal(err)
+	}
+	eh, err := ParseFile(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for idx, host := range eh.Hosts() {
+		if idx%100 == 1 {
+			host.SetAddress("10.255.255.255")
+		}
+	}
+	eh.InsertHost(NewHost("10.255.0.1", "added.example.com"), 10)
+	if err = os.WriteFile(path, []byte(content+"10.255.0.2 disk.example.com\n"), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = eh.Merge(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	// a blocklist import inserts every line in the middle and an edit of
+	// every other entry changes lines all through the file
+	before := benchContent(benchHosts / 5)
+	inserted := strings.Replace(before, "\n\n", "\n\n"+benchContent(benchHosts/5), 1)
+	lines := strings.SplitAfter(before, "\n")
+	for i := 3; i < len(lines); i += 2 {
+		lines[i] = "#" + lines[i]
+	}
+	edited := strings.Join(lines, "")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		unifiedDiff("hosts", before, inserted, DiffContext)
+		unifiedDiff("hosts", before, edited, DiffContext)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+)
+
+// BlockAddress and BlockAddressIPv6 are the unroutable addresses given to
+// the domains of a blocklist
+const (
+	BlockAddress     = "0.0.0.0"
+	BlockAddressIPv6 = "::"
+)
+
+// blocklistSinks are the addresses hosts-format blocklists point domains at
+var blocklistSinks = map[string]bool{
+	"0.0.0.0":   true,
+	"127.0.0.1": true,
+	"::":        true,
+	"::1":       true,
+}
+
+// blocklistIgnored are the local names hosts-format blocklists include
+// alongside the blocked domains
+var blocklistIgnored = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// ParseBlocklist reads the domains of a blocklist, with one rule per line in
+// any of these formats:
+//
+//	0.0.0.0 ads.example.com    # hosts format, as in StevenBlack/hosts
+//	ads.example.com            # one domain per line
+//	||ads.example.com^         # Adblock, rules with options are skipped
+//
+// Blank lines, comments and headers are ignored. The domains are lowercased
+// and deduplicated and skipped counts the rules that were not understood or
+// do not block a single valid domain
+func ParseBlocklist(r io.Reader) (domains []string, skipped int, err error) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			// lists saved on windows may start with a byte order mark
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		found, ok := parseBlocklistLine(line)
+		if !ok {
+			skipped += 1
+			continue
+		}
+		for _, domain := range found {
+			if !seen[domain] {
+				seen[domain] = true
+				domains = append(domains, domain)
+			}
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+// parseBlocklistLine returns the domains of one blocklist rule, ok is false
+// for rules which are not understood
+func parseBlocklistLine(line string) (domains []string, ok bool) {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "", line[0] == '#', line[0] == '!', line[0] == '[':
+		// blank, comment or an Adblock header
+		return nil, true
+	case strings.HasPrefix(line, "||"):
+		domain, found := strings.CutSuffix(line[2:], "^")
+		if !found {
+			return nil, false
+		}
+		return blocklistDomains(domain)
+	}
+
+	// a comment starts with a field beginning with #, example.com##.ad is an
+	// Adblock element hiding rule which must not block all of example.com
+	fields := strings.Fields(line)
+	for idx, field := range fields {
+		if strings.HasPrefix(field, "#") {
+			fields = fields[:idx]
+			break
+		}
+	}
+	if len(fields) == 1 {
+		return blocklistDomains(fields[0])
+	}
+	// hosts format, the address may have an IPv6 zone as in fe80::1%lo0
+	address, _, _ := strings.Cut(fields[0], "%")
+	if net.ParseIP(address) == nil {
+		return nil, false
+	}
+	var names []string
+	for _, field := range fields[1:] {
+		if !blocklistIgnored[strings.ToLower(field)] {
+			names = append(names, field)
+		}
+	}
+	if len(names) > 0 && !blocklistSinks[address] {
+		// not a blocking entry
+		return nil, false
+	}
+	for _, name := range names {
+		var valid []string
+		if valid, ok = blocklistDomains(name); !ok {
+			return nil, false
+		}
+		domains = append(domains, valid...)
+	}
+	return domains, true
+}
+
+func blocklistDomains(domain string) (domains []string, ok bool) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if blocklistIgnored[domain] {
+		return nil, true
+	} else if net.ParseIP(domain) != nil {
+		return nil, false
+	} else if _, valid := validHostname(domain); !valid {
+		return nil, false
+	}
+	return []string{domain}, true
+}
+
+// IsBlocked reports whether the host is an entry pointing its domains at one
+// of the BlockAddress addresses
+func (h *Host) IsBlocked() bool {
+	h.RLock()
+	defer h.RUnlock()
+	return !h.onlyComment && (h.address == BlockAddress || h.address == BlockAddressIPv6)
+}
+
+// Block adds a BlockAddress entry for each of the domains not already in the
+// hosts file, and a BlockAddressIPv6 entry as well when ipv6 is true. The new
+// entries are placed after any existing blocking entries, or else at the end
+// of the file, and the domains added are returned
+func (eh *Hostfile) Block(domains []string, ipv6 bool) (added []string) {
+	eh.Lock()
+	defer eh.Unlock()
+
+	present := make(map[string]bool)
+	at := len(eh.hosts)
+	for idx, host := range eh.hosts {
+		if host.IsOnlyComment() {
+			continue
+		}
+		for _, domain := range host.Domains() {
+			present[strings.ToLower(domain)] = true
+		}
+		if host.IsBlocked() {
+			at = idx + 1
+		}
+	}
+
+	var blocked []*Host
+	for _, domain := range domains {
+		key := strings.ToLower(domain)
+		if present[key] {
+			continue
+		}
+		present[key] = true
+		added = append(added, domain)
+		blocked = append(blocked, NewHost(BlockAddress, domain))
+		if ipv6 {
+			blocked = append(blocked, NewHost(BlockAddressIPv6, domain))
+		}
+	}
+	if len(blocked) > 0 {
+		hosts := make([]*Host, 0, len(eh.hosts)+len(blocked))
+		hosts = append(hosts, eh.hosts[:at]...)
+		hosts = append(hosts, blocked...)
+		eh.setHosts(append(hosts, eh.hosts[at:]...))
+	}
+	return
+}
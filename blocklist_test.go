@@ -0,0 +1,169 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBlocklistLine(t *testing.T) {
+	for _, test := range []struct {
+		line    string
+		domains []string
+		ok      bool
+	}{
+		{"", nil, true},
+		{"   ", nil, true},
+		{"# comment", nil, true},
+		{"! Adblock comment", nil, true},
+		{"[Adblock Plus 2.0]", nil, true},
+		{"0.0.0.0 ads.example.com", []string{"ads.example.com"}, true},
+		{"127.0.0.1\tads.example.com", []string{"ads.example.com"}, true},
+		{":: ads.example.com", []string{"ads.example.com"}, true},
+		{"::1 ads.example.com", []string{"ads.example.com"}, true},
+		{"0.0.0.0 ads.example.com track.example.com # two", []string{"ads.example.com", "track.example.com"}, true},
+		{"0.0.0.0 ADS.Example.COM.", []string{"ads.example.com"}, true},
+		{"  0.0.0.0 ads.example.com  \r", []string{"ads.example.com"}, true},
+		{"fe80::1%lo0 ads.example.com", nil, false},
+		{"127.0.0.1 localhost", nil, true},
+		{"::1 localhost ip6-localhost ip6-loopback", nil, true},
+		{"255.255.255.255 broadcasthost", nil, true},
+		{"0.0.0.0 0.0.0.0", nil, true},
+		{"0.0.0.0", nil, true},
+		{"192.168.0.1 router.lan", nil, false},
+		{"0.0.0.0 bad_name.example.com", nil, false},
+		{"0.0.0.0 ads.example.com bad_name.example.com", nil, false},
+		{"0.0.0.0 10.0.0.1", nil, false},
+		{"not-an-address ads.example.com", nil, false},
+		{"ads.example.com", []string{"ads.example.com"}, true},
+		{"ads.example.com # one per line", []string{"ads.example.com"}, true},
+		{"localhost", nil, true},
+		{"10.0.0.1", nil, false},
+		{"-ads.example.com", nil, false},
+		{"||ads.example.com^", []string{"ads.example.com"}, true},
+		{"||Ads.Example.com^", []string{"ads.example.com"}, true},
+		{"||ads.example.com^$third-party", nil, false},
+		{"||*.example.com^", nil, false},
+		{"||ads.example.com", nil, false},
+		{"||^", nil, false},
+		{"@@||ads.example.com^", nil, false},
+		{"/banner/*/img^", nil, false},
+		{"example.com##.ad", nil, false},
+	} {
+		domains, ok := parseBlocklistLine(test.line)
+		if !reflect.DeepEqual(domains, test.domains) || ok != test.ok {
+			t.Errorf("parseBlocklistLine(%q) = %q, %v, want %q, %v", test.line, domains, ok, test.domains, test.ok)
+		}
+	}
+}
+
+func TestParseBlocklist(t *testing.T) {
+	const content = "\ufeff# Title: a blocklist\r\n" +
+		"127.0.0.1 localhost\r\n" +
+		"0.0.0.0 ads.example.com\r\n" +
+		"0.0.0.0 track.example.com\r\n" +
+		"\r\n" +
+		"||ADS.example.com^\r\n" +
+		"||cdn.example.com^$script\r\n" +
+		"pixel.example.com\r\n" +
+		"192.168.0.1 router.lan\r\n"
+	domains, skipped, err := ParseBlocklist(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ads.example.com", "track.example.com", "pixel.example.com"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("ParseBlocklist() domains = %q, want %q", domains, want)
+	}
+	if skipped != 2 {
+		t.Errorf("ParseBlocklist() skipped = %d, want 2", skipped)
+	}
+
+	if _, _, err = ParseBlocklist(strings.NewReader(strings.Repeat("a", 70000))); err == nil {
+		t.Errorf("ParseBlocklist() of a line too long did not fail")
+	}
+}
+
+func TestBlock(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		domains []string
+		ipv6    bool
+		added   []string
+		want    string
+	}{
+		{
+			"at the end",
+			"127.0.0.1 localhost\n10.0.0.1 one.lan\n",
+			[]string{"ads.example.com", "track.example.com"}, false,
+			[]string{"ads.example.com", "track.example.com"},
+			"127.0.0.1 localhost\n10.0.0.1 one.lan\n\n0.0.0.0\tads.example.com\n0.0.0.0\ttrack.example.com\n",
+		},
+		{
+			"after blocked entries",
+			"127.0.0.1 localhost\n0.0.0.0 old.example.com\n10.0.0.1 one.lan\n",
+			[]string{"ads.example.com"}, false,
+			[]string{"ads.example.com"},
+			"127.0.0.1 localhost\n0.0.0.0 old.example.com\n0.0.0.0\tads.example.com\n10.0.0.1 one.lan\n",
+		},
+		{
+			"without a final newline",
+			"127.0.0.1 localhost",
+			[]string{"ads.example.com"}, false,
+			[]string{"ads.example.com"},
+			"127.0.0.1 localhost\n\n0.0.0.0\tads.example.com",
+		},
+		{
+			"with ipv6",
+			"127.0.0.1 localhost\n",
+			[]string{"ads.example.com"}, true,
+			[]string{"ads.example.com"},
+			"127.0.0.1 localhost\n\n0.0.0.0\tads.example.com\n::\tads.example.com\n",
+		},
+		{
+			"already present",
+			"127.0.0.1 localhost\n10.0.0.1 Ads.Example.com\n#0.0.0.0 off.example.com\n",
+			[]string{"ads.example.com", "off.example.com", "new.example.com", "new.example.com"}, false,
+			[]string{"new.example.com"},
+			"127.0.0.1 localhost\n10.0.0.1 Ads.Example.com\n#0.0.0.0 off.example.com\n0.0.0.0\tnew.example.com\n",
+		},
+		{
+			"already present ignoring case",
+			"127.0.0.1 localhost\n0.0.0.0 ads.example.com\n",
+			[]string{"ADS.example.com", "New.Example.com", "new.example.com"}, false,
+			[]string{"New.Example.com"},
+			"127.0.0.1 localhost\n0.0.0.0 ads.example.com\n0.0.0.0\tNew.Example.com\n",
+		},
+		{
+			"nothing to add",
+			"0.0.0.0 ads.example.com\n",
+			[]string{"ads.example.com"}, true,
+			nil,
+			"0.0.0.0 ads.example.com\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			if added := eh.Block(test.domains, test.ipv6); !reflect.DeepEqual(added, test.added) {
+				t.Errorf("Block() = %q, want %q", added, test.added)
+			}
+			if got := eh.String(); got != test.want {
+				t.Errorf("Block() hosts =\n%v\nwant\n%v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+var blockCommand = editCommand{
+	Name:      "block",
+	ArgsUsage: "[blocklist...]",
+	Usage:     "add 0.0.0.0 entries for the domains of hosts, domain or adblock lists",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "ipv6",
+			Usage:   "also add :: entries for each domain",
+			Aliases: []string{"6"},
+		},
+	},
+	Action: blockAction,
+}
+
+func blockAction(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+	inputs := ctx.Args().Slice()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	var domains []string
+	var skipped int
+	for _, input := range inputs {
+		var found []string
+		var count int
+		if found, count, err = readBlocklist(input); err != nil {
+			return
+		}
+		domains = append(domains, found...)
+		skipped += count
+	}
+
+	added := eh.Block(domains, ctx.Bool("ipv6"))
+	_, _ = fmt.Fprintf(ctx.App.ErrWriter,
+		"%d of %d domains blocked, %d already present, %d rules skipped\n",
+		len(added), len(domains), len(domains)-len(added), skipped,
+	)
+	changed = len(added) > 0
+	return
+}
+
+func readBlocklist(input string) (domains []string, skipped int, err error) {
+	var r io.Reader = os.Stdin
+	if input != "-" {
+		var fh *os.File
+		if fh, err = os.Open(input); err != nil {
+			return
+		}
+		defer func() { _ = fh.Close() }()
+		r = fh
+	}
+	return editor.ParseBlocklist(r)
+}
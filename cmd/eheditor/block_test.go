@@ -0,0 +1,58 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	content := "# a blocklist\n0.0.0.0 ads.example.com\n||track.example.com^\nbad_name.example.com\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ads.example.com", "track.example.com"}
+
+	if domains, skipped, err := readBlocklist(path); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(domains, want) || skipped != 1 {
+		t.Errorf("readBlocklist(%v) = %q, %d, want %q, 1", path, domains, skipped, want)
+	}
+
+	stdin, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(original *os.File) {
+		os.Stdin = original
+		_ = stdin.Close()
+	}(os.Stdin)
+	os.Stdin = stdin
+	if domains, skipped, err := readBlocklist("-"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(domains, want) || skipped != 1 {
+		t.Errorf("readBlocklist(-) = %q, %d, want %q, 1", domains, skipped, want)
+	}
+
+	if _, _, err := readBlocklist(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("readBlocklist() of a missing file error = %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// exit codes of the check command, checkUsage being for an invalid command
+// line so that it is not mistaken for a file with errors
+const (
+	checkClean    = 0
+	checkWarnings = 1
+	checkErrors   = 2
+	checkUsage    = 3
+)
+
+var checkFormatFlag = &cli.StringFlag{
+	Name:    "format",
+	Usage:   "text, json or github (workflow command annotations)",
+	Value:   "text",
+	Aliases: []string{"F"},
+}
+
+// checkResult is one finding of the check command, as written in json format
+type checkResult struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+
+	severity editor.Severity
+}
+
+func makeCheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "check",
+		Usage:     "validate hosts files, exiting 1 for warnings and 2 for errors",
+		ArgsUsage: "[file...]",
+		Flags:     []cli.Flag{checkFormatFlag},
+		Action:    checkAction,
+	}
+}
+
+func checkAction(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	switch format {
+	case "text", "json", "github":
+	default:
+		return cli.Exit(fmt.Sprintf("check: unsupported format: %q", format), checkUsage)
+	}
+
+	paths := ctx.Args().Slice()
+	if len(paths) == 0 {
+		paths = []string{editor.DefaultHostsFile}
+	}
+
+	results := make([]checkResult, 0)
+	for _, path := range paths {
+		results = append(results, checkFile(path)...)
+	}
+
+	code := checkClean
+	for _, result := range results {
+		switch {
+		case result.severity == editor.SeverityError:
+			code = checkErrors
+		case result.severity == editor.SeverityWarning && code == checkClean:
+			code = checkWarnings
+		}
+	}
+
+	w := ctx.App.Writer
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(results); err != nil {
+			return cli.Exit(fmt.Sprintf("check: %v", err), checkErrors)
+		}
+	case "github":
+		for _, result := range results {
+			_, _ = fmt.Fprintln(w, result.annotation())
+		}
+	default:
+		for _, result := range results {
+			_, _ = fmt.Fprintln(w, result.String())
+		}
+	}
+
+	if code != checkClean {
+		return cli.Exit("", code)
+	}
+	return
+}
+
+// checkFile returns the parse diagnostics and validation findings of the
+// file, ordered by line. Parse diagnostics repeated by a finding are left out
+func checkFile(path string) (results []checkResult) {
+	eh, report, err := editor.ParseFileWithReport(path)
+	if err != nil {
+		return []checkResult{{
+			File:     path,
+			Severity: editor.SeverityError.String(),
+			Rule:     "parse",
+			Message:  err.Error(),
+			severity: editor.SeverityError,
+		}}
+	}
+
+	findings := eh.Validate()
+	reported := make(map[string]bool)
+	for _, finding := range findings {
+		reported[fmt.Sprintf("%d:%v", finding.Line, finding.Message)] = true
+	}
+	for _, diagnostic := range report {
+		if reported[fmt.Sprintf("%d:%v", diagnostic.Line, diagnostic.Reason)] {
+			continue
+		}
+		results = append(results, checkResult{
+			File:     path,
+			Line:     diagnostic.Line,
+			Severity: diagnostic.Severity.String(),
+			Rule:     "parse",
+			Message:  diagnostic.Reason,
+			severity: diagnostic.Severity,
+		})
+	}
+	for _, finding := range findings {
+		results = append(results, checkResult{
+			File:     path,
+			Line:     finding.Line,
+			Severity: finding.Severity.String(),
+			Rule:     finding.Rule,
+			Message:  finding.Message,
+			severity: finding.Severity,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		li, lj := results[i].Line, results[j].Line
+		if li == 0 || lj == 0 {
+			return li != 0 && lj == 0
+		}
+		return li < lj
+	})
+	return
+}
+
+// String returns the result in compiler-style "file:line: severity: message
+// (rule)" form
+func (r checkResult) String() string {
+	location := r.File
+	if r.Line > 0 {
+		location += fmt.Sprintf(":%d", r.Line)
+	}
+	return fmt.Sprintf("%v: %v: %v (%v)", location, r.Severity, r.Message, r.Rule)
+}
+
+// annotation returns the result as a GitHub Actions workflow command
+func (r checkResult) annotation() string {
+	command := "notice"
+	switch r.severity {
+	case editor.SeverityError:
+		command = "error"
+	case editor.SeverityWarning:
+		command = "warning"
+	}
+	properties := "file=" + escapeAnnotation(r.File, true)
+	if r.Line > 0 {
+		properties += fmt.Sprintf(",line=%d", r.Line)
+	}
+	properties += ",title=" + escapeAnnotation(r.Rule, true)
+	return fmt.Sprintf("::%v %v::%v", command, properties, escapeAnnotation(r.Message, false))
+}
+
+func escapeAnnotation(value string, property bool) string {
+	value = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+	if property {
+		value = strings.NewReplacer(":", "%3A", ",", "%2C").Replace(value)
+	}
+	return value
+}
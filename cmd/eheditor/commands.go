@@ -0,0 +1,298 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	cstrings "github.com/go-curses/cdk/lib/strings"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+var fileFlag = &cli.StringFlag{
+	Name:    "file",
+	Usage:   "path to the etc hosts file to use",
+	Value:   editor.DefaultHostsFile,
+	Aliases: []string{"f"},
+}
+
+// editAction is the part of a command which modifies the hosts file, it
+// returns true if anything was changed
+type editAction func(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error)
+
+// editCommand describes a command which modifies the hosts file, made into
+// either the command itself or its dry-run form under diff
+type editCommand struct {
+	Name      string
+	ArgsUsage string
+	Usage     string
+	Flags     []cli.Flag
+	Action    editAction
+}
+
+var editCommands = []editCommand{
+	{"add", "<ip> <domain...>", "add a host entry, or activate an inactive one, unless the domains are present", nil, addAction},
+	{"remove", "<domain>", "remove a domain, and any entry left without domains", nil, removeAction},
+	{"enable", "<domain>", "activate all entries with the domain", nil, activateAction(true)},
+	{"disable", "<domain>", "deactivate all entries with the domain", nil, activateAction(false)},
+	{"set-address", "<domain> <ip>", "change the address of all entries with the domain", nil, setAddressAction},
+	{"repair", "", "add the missing required entries at the top of the file", nil, repairAction},
+}
+
+func makeCommands() (commands []*cli.Command) {
+	commands = append(commands, &cli.Command{
+		Name:   "list",
+		Usage:  "list the host entries",
+		Flags:  []cli.Flag{fileFlag},
+		Action: listAction,
+	})
+	for _, command := range editCommands {
+		commands = append(commands, command.Make(false))
+	}
+	return
+}
+
+// Make returns the cli command, which either saves the changes or, when
+// dryRun is true, only prints the unified diff of what would be saved
+func (e editCommand) Make(dryRun bool) *cli.Command {
+	return &cli.Command{
+		Name:      e.Name,
+		Usage:     e.Usage,
+		ArgsUsage: e.ArgsUsage,
+		Flags:     append([]cli.Flag{fileFlag}, e.Flags...),
+		Action: func(ctx *cli.Context) (err error) {
+			var eh *editor.Hostfile
+			if eh, err = loadHostfile(ctx); err != nil {
+				return
+			}
+			var changed bool
+			if changed, err = e.Action(ctx, eh); err != nil {
+				return cli.Exit(fmt.Sprintf("%v: %v", e.Name, err), 1)
+			} else if dryRun {
+				return printDiff(ctx, eh)
+			} else if !changed {
+				return
+			}
+			return saveHostfile(ctx, eh)
+		},
+	}
+}
+
+func loadHostfile(ctx *cli.Context) (eh *editor.Hostfile, err error) {
+	path := ctx.String("file")
+	if eh, err = editor.ParseFile(path); err != nil {
+		return nil, cli.Exit(fmt.Sprintf("error parsing %v: %v", path, err), 1)
+	}
+	eh.Backups = ctx.Int("backups")
+	return
+}
+
+func saveHostfile(ctx *cli.Context, eh *editor.Hostfile) (err error) {
+	if ctx.Bool("read-only") {
+		return cli.Exit(fmt.Sprintf("%v: changes not saved in read-only mode", eh.Path), 1)
+	}
+	if err = eh.Save(); err != nil {
+		return cli.Exit(fmt.Sprintf("error saving %v: %v", eh.Path, err), 1)
+	}
+	return
+}
+
+func requireArgs(ctx *cli.Context, count int) (args []string, err error) {
+	if args = ctx.Args().Slice(); len(args) < count {
+		err = fmt.Errorf("expected at least %d arguments, see --help", count)
+	}
+	return
+}
+
+func listAction(ctx *cli.Context) (err error) {
+	var eh *editor.Hostfile
+	if eh, err = loadHostfile(ctx); err != nil {
+		return
+	}
+	for _, host := range eh.Hosts() {
+		if host.IsOnlyComment() || host.Empty() {
+			continue
+		}
+		var active string
+		if !host.Active() {
+			active = "#"
+		}
+		_, _ = fmt.Fprintf(ctx.App.Writer, "%v%v\t%v\n", active, host.Address(), strings.Join(host.Domains(), " "))
+	}
+	return
+}
+
+func addAction(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+	var args []string
+	if args, err = requireArgs(ctx, 2); err != nil {
+		return
+	}
+	address, domains := args[0], args[1:]
+	if !cstrings.StringIsIP(address) {
+		err = fmt.Errorf("%q is not a valid IP address", address)
+		return
+	}
+
+	var missing []string
+	var inactive []*editor.Host
+	for _, domain := range domains {
+		var present bool
+		var found *editor.Host
+		for _, host := range eh.Hosts() {
+			if host.IsOnlyComment() || !host.HasDomain(domain) {
+				continue
+			} else if !host.Active() {
+				if found == nil && host.Address() == address {
+					found = host
+				}
+				continue
+			}
+			if host.Address() != address {
+				err = fmt.Errorf("%v is already assigned to %v, see set-address", domain, host.Address())
+				return
+			}
+			present = true
+		}
+		if present {
+			continue
+		} else if found != nil {
+			// an inactive entry of the same address is activated instead
+			inactive = append(inactive, found)
+		} else {
+			missing = append(missing, domain)
+		}
+	}
+
+	for _, host := range inactive {
+		if !host.Active() {
+			host.SetActive(true)
+			changed = true
+		}
+	}
+	if len(missing) > 0 {
+		eh.InsertHost(editor.NewHost(address, missing...), eh.Len())
+		changed = true
+	}
+	return
+}
+
+func removeAction(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+	var args []string
+	if args, err = requireArgs(ctx, 1); err != nil {
+		return
+	}
+	domain := args[0]
+	for _, host := range eh.Hosts() {
+		if host.IsOnlyComment() || !host.HasDomain(domain) {
+			continue
+		}
+		if host.IsProtected() {
+			err = fmt.Errorf("refusing to remove required entry %v", domain)
+			return
+		}
+	}
+	for idx := eh.Len() - 1; idx >= 0; idx-- {
+		host := eh.Hosts()[idx]
+		if host.IsOnlyComment() || !host.HasDomain(domain) {
+			continue
+		}
+		changed = true
+		if host.RemoveDomain(domain); len(host.Domains()) == 0 {
+			eh.RemoveHost(host)
+		}
+	}
+	return
+}
+
+func activateAction(active bool) editAction {
+	return func(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+		var args []string
+		if args, err = requireArgs(ctx, 1); err != nil {
+			return
+		}
+		domain := args[0]
+		var hosts []*editor.Host
+		for _, host := range eh.Hosts() {
+			if host.IsOnlyComment() || !host.HasDomain(domain) {
+				continue
+			}
+			if !active && host.Active() && host.IsProtected() {
+				err = fmt.Errorf("refusing to deactivate required entry %v", domain)
+				return
+			}
+			hosts = append(hosts, host)
+		}
+		if len(hosts) == 0 {
+			err = fmt.Errorf("%v not found", domain)
+			return
+		}
+		for _, host := range hosts {
+			if host.Active() != active {
+				host.SetActive(active)
+				changed = true
+			}
+		}
+		return
+	}
+}
+
+func setAddressAction(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+	var args []string
+	if args, err = requireArgs(ctx, 2); err != nil {
+		return
+	}
+	domain, address := args[0], args[1]
+	if !cstrings.StringIsIP(address) {
+		err = fmt.Errorf("%q is not a valid IP address", address)
+		return
+	}
+	var hosts []*editor.Host
+	for _, host := range eh.Hosts() {
+		if host.IsOnlyComment() || !host.HasDomain(domain) {
+			continue
+		}
+		if host.Address() != address && host.IsProtected() {
+			err = fmt.Errorf("refusing to change the address of required entry %v", domain)
+			return
+		}
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		err = fmt.Errorf("%v not found", domain)
+		return
+	}
+	for _, host := range hosts {
+		if host.Address() != address {
+			host.SetAddress(address)
+			changed = true
+		}
+	}
+	return
+}
+
+func repairAction(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+	added := eh.Repair()
+	for _, host := range added {
+		_, _ = fmt.Fprintf(ctx.App.ErrWriter, "adding: %v\n", strings.TrimSpace(host.Line()))
+	}
+	if changed = len(added) > 0; !changed {
+		_, _ = fmt.Fprintln(ctx.App.ErrWriter, "nothing to repair")
+	}
+	return
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// runCommand runs the command, which may be a subcommand such as "diff add",
+// with the args against a hosts file of the content and returns what was
+// printed and the content of the hosts file afterwards
+func runCommand(t *testing.T, content, command string, args ...string) (output, saved string, err error) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	app := &cli.App{
+		Name:           "eheditor",
+		Commands:       append(makeCommands(), makeDiffCommand()),
+		Writer:         &buffer,
+		ErrWriter:      &buffer,
+		ExitErrHandler: func(*cli.Context, error) {},
+	}
+	argv := append([]string{"eheditor"}, strings.Fields(command)...)
+	err = app.Run(append(append(argv, "--file", path), args...))
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return buffer.String(), string(data), err
+}
+
+func TestEditCommands(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		command string
+		args    []string
+		want    string
+		err     string
+	}{
+		{"add", "127.0.0.1 localhost\n", "add", []string{"10.0.0.1", "foo", "bar"}, "127.0.0.1 localhost\n\n10.0.0.1\tfoo bar\n", ""},
+		{"add present", "10.0.0.1 foo\n", "add", []string{"10.0.0.1", "foo"}, "10.0.0.1 foo\n", ""},
+		{"add some present", "10.0.0.1 foo\n", "add", []string{"10.0.0.1", "foo", "bar"}, "10.0.0.1 foo\n\n10.0.0.1\tbar\n", ""},
+		{"add inactive", "#10.0.0.1 foo\n", "add", []string{"10.0.0.1", "foo"}, "10.0.0.1 foo\n", ""},
+		{"add inactive and new", "#10.0.0.1 foo\n", "add", []string{"10.0.0.1", "foo", "bar"}, "10.0.0.1 foo\n\n10.0.0.1\tbar\n", ""},
+		{"add inactive elsewhere", "#10.0.0.2 foo\n", "add", []string{"10.0.0.1", "foo"}, "#10.0.0.2 foo\n\n10.0.0.1\tfoo\n", ""},
+		{"add assigned", "10.0.0.2 foo\n#10.0.0.1 foo\n", "add", []string{"10.0.0.1", "foo"}, "10.0.0.2 foo\n#10.0.0.1 foo\n", "add: foo is already assigned to 10.0.0.2, see set-address"},
+		{"add invalid address", "", "add", []string{"10.0.0", "foo"}, "", `add: "10.0.0" is not a valid IP address`},
+		{"add without domains", "", "add", []string{"10.0.0.1"}, "", "add: expected at least 2 arguments, see --help"},
+		{"remove", "10.0.0.1 foo bar\n10.0.0.2 foo\n", "remove", []string{"foo"}, "10.0.0.1 bar\n", ""},
+		{"remove protected", "127.0.0.1 localhost\n", "remove", []string{"localhost"}, "127.0.0.1 localhost\n", "remove: refusing to remove required entry localhost"},
+		{"enable", "#10.0.0.1 foo\n#10.0.0.2 foo\n", "enable", []string{"foo"}, "10.0.0.1 foo\n10.0.0.2 foo\n", ""},
+		{"enable missing", "10.0.0.1 foo\n", "enable", []string{"bar"}, "10.0.0.1 foo\n", "enable: bar not found"},
+		{"disable", "10.0.0.1 foo\n", "disable", []string{"foo"}, "#10.0.0.1 foo\n", ""},
+		{"disable protected", "127.0.0.1 localhost\n", "disable", []string{"localhost"}, "127.0.0.1 localhost\n", "disable: refusing to deactivate required entry localhost"},
+		{"set-address", "10.0.0.1 foo\n10.0.0.2 foo\n", "set-address", []string{"foo", "10.0.0.9"}, "10.0.0.9 foo\n10.0.0.9 foo\n", ""},
+		{"set-address protected", "127.0.0.1 localhost\n", "set-address", []string{"localhost", "10.0.0.1"}, "127.0.0.1 localhost\n", "set-address: refusing to change the address of required entry localhost"},
+		{"set-address invalid", "10.0.0.1 foo\n", "set-address", []string{"foo", "bar"}, "10.0.0.1 foo\n", `set-address: "bar" is not a valid IP address`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, saved, err := runCommand(t, test.content, test.command, test.args...)
+			if test.err == "" && err != nil {
+				t.Errorf("%v %v error = %v", test.command, test.args, err)
+			} else if test.err != "" && (err == nil || err.Error() != test.err) {
+				t.Errorf("%v %v error = %v, want %v", test.command, test.args, err, test.err)
+			}
+			if saved != test.want {
+				t.Errorf("%v %v saved %q, want %q", test.command, test.args, saved, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	output, saved, err := runCommand(t, "10.0.0.1 foo\n", "diff disable", "foo")
+	if err != nil {
+		t.Fatal(err)
+	} else if saved != "10.0.0.1 foo\n" {
+		t.Errorf("diff disable saved %q", saved)
+	} else if !strings.Contains(output, "-10.0.0.1 foo\n+#10.0.0.1 foo\n") {
+		t.Errorf("diff disable printed %q", output)
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	output, _, err := runCommand(t, "# hosts\n10.0.0.1 foo bar\n#10.0.0.2 baz\n", "list")
+	if err != nil {
+		t.Fatal(err)
+	} else if want := "10.0.0.1\tfoo bar\n#10.0.0.2\tbaz\n"; output != want {
+		t.Errorf("list printed %q, want %q", output, want)
+	}
+}
+
+func TestRepairCommand(t *testing.T) {
+	content := "10.0.0.1 foo\n"
+	output, saved, err := runCommand(t, content, "repair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eh := mustParseHosts(t, saved)
+	for _, entry := range editor.DefaultPolicy.RepairEntries() {
+		for _, domain := range entry.Domains {
+			if hosts := eh.HostsForDomain(domain); len(hosts) == 0 {
+				t.Errorf("repair did not add %v:\n%v", domain, saved)
+			}
+		}
+	}
+	if !strings.HasSuffix(saved, content) || !strings.Contains(output, "adding: ") {
+		t.Errorf("repair saved %q and printed %q", saved, output)
+	}
+
+	if output, again, err := runCommand(t, saved, "repair"); err != nil {
+		t.Fatal(err)
+	} else if again != saved || output != "nothing to repair\n" {
+		t.Errorf("repair again saved %q and printed %q", again, output)
+	}
+}
+
+// TestEditActionsFailUnchanged checks the actions refusing to change a
+// protected entry do so before changing any of the others
+func TestEditActionsFailUnchanged(t *testing.T) {
+	const content = "10.0.0.1 foo\n127.0.0.1 localhost foo\n"
+	for _, test := range []struct {
+		name   string
+		action editAction
+		args   []string
+	}{
+		{"disable", activateAction(false), []string{"foo"}},
+		{"set-address", setAddressAction, []string{"foo", "10.0.0.9"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParseHosts(t, content)
+			set := flag.NewFlagSet(test.name, flag.ContinueOnError)
+			if err := set.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			if changed, err := test.action(cli.NewContext(&cli.App{}, set, nil), eh); err == nil || changed {
+				t.Errorf("%v = %v, %v; want an error", test.name, changed, err)
+			} else if got := eh.String(); got != content {
+				t.Errorf("%v changed the hosts to %q", test.name, got)
+			}
+		})
+	}
+}
+
+func mustParseHosts(t *testing.T, content string) *editor.Hostfile {
+	t.Helper()
+	eh, err := editor.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eh
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-curses/cdk/lib/paint"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+	"github.com/go-curses/coreutils-etc-hosts-editor/ui"
+)
+
+// configSettings are the keys of a config file, in the order the config
+// command prints them:
+//
+//	file = "/etc/hosts"      # hosts file used without --file or an argument
+//	read-only = false        # do not write any changes to the hosts file
+//	backups = 3              # timestamped backups to keep when saving
+//	sidebar = "domain"       # list the sidebar by domain, address or entry
+//	theme = "navy"           # background color, a color name or #rrggbb
+//	dns-server = ""          # host[:port] of the DNS server for nslookups
+//	dns-timeout = "5s"       # time limit for each nslookup
+//	presets = ""             # directory of presets, see --presets
+//	policy = ""              # policy file, see --policy
+//
+// All but file are the global flag of the same name, which overrides them
+var configSettings = []string{"file", "read-only", "backups", "sidebar", "theme", "dns-server", "dns-timeout", "presets", "policy"}
+
+// configSources is where each setting which is not the default came from,
+// see loadConfig
+var configSources = make(map[string]string)
+
+// configFiles returns the config files to read, a later one overriding the
+// settings of the earlier ones
+func configFiles() (paths []string) {
+	paths = append(paths, "/etc/eheditor.toml")
+	if config, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(config, "eheditor", "config.toml"))
+	}
+	return
+}
+
+// loadConfig reads the configFiles which exist and sets the global flags
+// not given on the command line to the settings found
+func loadConfig(ctx *cli.Context) (err error) {
+	for _, name := range configSettings {
+		if name != "file" && ctx.IsSet(name) {
+			configSources[name] = "--" + name
+		}
+	}
+	for _, path := range configFiles() {
+		if err = readConfig(ctx, path); err != nil {
+			return cli.Exit(fmt.Sprintf("error reading %v: %v", path, err), 1)
+		}
+	}
+	if name := ctx.String("sidebar"); name != "" {
+		if _, ok := ui.ParseSidebarListMode(name); !ok {
+			return cli.Exit(fmt.Sprintf("unknown sidebar listing %q, expected domain, address or entry", name), 1)
+		}
+	}
+	if theme := ctx.String("theme"); theme != "" {
+		if _, ok := paint.ParseColor(theme); !ok {
+			return cli.Exit(fmt.Sprintf("unknown theme color %q, expected a color name or #rrggbb", theme), 1)
+		}
+	}
+	return
+}
+
+func readConfig(ctx *cli.Context, path string) (err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+
+	var values map[string]interface{}
+	if err = toml.Unmarshal(data, &values); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, _ := decodeErr.Position()
+			err = fmt.Errorf("line %d: %v", row, decodeErr)
+		}
+		return
+	}
+	lines := settingLines(data)
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return lines[names[i]] < lines[names[j]]
+	})
+
+	for _, name := range names {
+		if configSources[name] == "--"+name {
+			continue
+		}
+		if err = applySetting(ctx, name, values[name]); err != nil {
+			return fmt.Errorf("line %d: %v: %v", lines[name], name, err)
+		}
+		configSources[name] = fmt.Sprintf("%v:%d", path, lines[name])
+	}
+	return
+}
+
+// settingLines returns the line number of each top-level key in the config
+// file data, which must be valid TOML
+func settingLines(data []byte) (lines map[string]int) {
+	lines = make(map[string]int)
+	var parser unstable.Parser
+	parser.Reset(data)
+	for parser.NextExpression() {
+		switch expression := parser.Expression(); expression.Kind {
+		case unstable.KeyValue, unstable.Table, unstable.ArrayTable:
+			// the keys of a table follow its own, so the first is the top-level
+			if keys := expression.Key(); keys.Next() {
+				name := string(keys.Node().Data)
+				if _, found := lines[name]; !found {
+					lines[name] = parser.Shape(keys.Node().Raw).Start.Line
+				}
+			}
+		}
+	}
+	return
+}
+
+// applySetting sets the global flag of the name to the value, which must be
+// of the type the flag takes
+func applySetting(ctx *cli.Context, name string, value interface{}) (err error) {
+	if name == "file" {
+		path, ok := value.(string)
+		if !ok || path == "" {
+			return fmt.Errorf("expected a path")
+		}
+		editor.DefaultHostsFile = path
+		fileFlag.Value = path
+		return
+	}
+
+	var flag cli.Flag
+	for _, setting := range configSettings {
+		if setting == name {
+			flag = findFlag(ctx.App.Flags, name)
+		}
+	}
+
+	var text string
+	switch flag.(type) {
+	case nil:
+		return fmt.Errorf("unknown setting")
+	case *cli.BoolFlag:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected true or false")
+		}
+		text = strconv.FormatBool(b)
+	case *cli.IntFlag:
+		i, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("expected a number")
+		}
+		text = strconv.FormatInt(i, 10)
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string")
+		}
+		text = s
+	}
+	if err = ctx.Set(name, text); err != nil {
+		err = fmt.Errorf("invalid value %q", text)
+	}
+	return
+}
+
+func findFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, flag := range flags {
+		for _, other := range flag.Names() {
+			if other == name {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+func makeConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "config",
+		Usage:  "print the effective configuration and where each setting came from",
+		Action: configAction,
+	}
+}
+
+func configAction(ctx *cli.Context) (err error) {
+	w := tabwriter.NewWriter(ctx.App.Writer, 0, 0, 2, ' ', 0)
+	for _, path := range configFiles() {
+		if _, err := os.Stat(path); err != nil {
+			_, _ = fmt.Fprintf(w, "# %v (not read: %v)\n", path, errors.Unwrap(err))
+		} else {
+			_, _ = fmt.Fprintf(w, "# %v\n", path)
+		}
+	}
+	for _, name := range configSettings {
+		var value interface{} = editor.DefaultHostsFile
+		if name != "file" {
+			value = ctx.Value(name)
+		}
+		switch v := value.(type) {
+		case string:
+			value = strconv.Quote(v)
+		case time.Duration:
+			value = strconv.Quote(v.String())
+		}
+		source := configSources[name]
+		if source == "" {
+			source = "default"
+		}
+		_, _ = fmt.Fprintf(w, "%v = %v\t# %v\n", name, value, source)
+	}
+	return w.Flush()
+}
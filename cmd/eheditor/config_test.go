@@ -0,0 +1,119 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// runConfig reads the config content with the global flags of the same
+// types as the app's, given the args on the command line
+func runConfig(t *testing.T, content string, args ...string) (ctx *cli.Context, err error) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configSources = make(map[string]string)
+	app := &cli.App{
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "read-only", Aliases: []string{"r"}},
+			&cli.IntFlag{Name: "backups", Value: 3},
+			&cli.StringFlag{Name: "sidebar", Value: "domain"},
+			&cli.DurationFlag{Name: "dns-timeout", Value: editor.DefaultLookupTimeout},
+		},
+		Action: func(c *cli.Context) error {
+			for _, name := range configSettings {
+				if name != "file" && c.IsSet(name) {
+					configSources[name] = "--" + name
+				}
+			}
+			ctx = c
+			return readConfig(c, path)
+		},
+	}
+	err = app.Run(append([]string{"eheditor"}, args...))
+	return
+}
+
+func TestReadConfig(t *testing.T) {
+	ctx, err := runConfig(t, "# settings\nread-only = true\nbackups = 5\nsidebar = \"address\"\ndns-timeout = \"2s\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ctx.Bool("read-only") || ctx.Int("backups") != 5 || ctx.String("sidebar") != "address" || ctx.Duration("dns-timeout") != 2*time.Second {
+		t.Errorf("settings not applied: read-only=%v backups=%v sidebar=%v dns-timeout=%v",
+			ctx.Bool("read-only"), ctx.Int("backups"), ctx.String("sidebar"), ctx.Duration("dns-timeout"))
+	}
+	if source := configSources["backups"]; filepath.Base(source) != "config.toml:3" {
+		t.Errorf("backups source = %q, want config.toml:3", source)
+	}
+}
+
+func TestReadConfigFlagsOverride(t *testing.T) {
+	ctx, err := runConfig(t, "backups = 5\nsidebar = \"address\"\n", "--backups", "1")
+	if err != nil {
+		t.Fatal(err)
+	} else if ctx.Int("backups") != 1 || ctx.String("sidebar") != "address" {
+		t.Errorf("backups = %v and sidebar = %v, want 1 and address", ctx.Int("backups"), ctx.String("sidebar"))
+	} else if configSources["backups"] != "--backups" {
+		t.Errorf("backups source = %q, want --backups", configSources["backups"])
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	defaultHostsFile, fileValue := editor.DefaultHostsFile, fileFlag.Value
+	defer func() {
+		editor.DefaultHostsFile, fileFlag.Value = defaultHostsFile, fileValue
+	}()
+	if _, err := runConfig(t, "file = \"/tmp/hosts\"\n"); err != nil {
+		t.Fatal(err)
+	} else if editor.DefaultHostsFile != "/tmp/hosts" || fileFlag.Value != "/tmp/hosts" {
+		t.Errorf("file = %q and %q, want /tmp/hosts", editor.DefaultHostsFile, fileFlag.Value)
+	}
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax", "backups = \n", "line 1: toml: incomplete number"},
+		{"later syntax", "backups = 5\n\nsidebar = \"address\n", "line 3: toml: basic strings cannot have new lines"},
+		{"duplicate", "backups = 5\nbackups = 6\n", "toml: key backups is already defined"},
+		{"table", "backups = 5\n[section]\nsidebar = \"address\"\n", "line 2: section: unknown setting"},
+		{"unknown", "colour = \"red\"\n", "line 1: colour: unknown setting"},
+		{"bool type", "read-only = \"yes\"\n", "line 1: read-only: expected true or false"},
+		{"int type", "backups = \"5\"\n", "line 1: backups: expected a number"},
+		{"float type", "backups = 5.0\n", "line 1: backups: expected a number"},
+		{"string type", "sidebar = 1\n", "line 1: sidebar: expected a string"},
+		{"duration", "dns-timeout = \"soon\"\n", `line 1: dns-timeout: invalid value "soon"`},
+		{"file type", "file = true\n", "line 1: file: expected a path"},
+		{"empty file", "file = \"\"\n", "line 1: file: expected a path"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := runConfig(t, test.content); err == nil || err.Error() != test.want {
+				t.Errorf("readConfig() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
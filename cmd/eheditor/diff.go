@@ -0,0 +1,43 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+func makeDiffCommand() *cli.Command {
+	command := &cli.Command{
+		Name:  "diff",
+		Usage: "show the unified diff of a command's changes, without saving",
+	}
+	for _, edit := range append(editCommands, importCommand, blockCommand) {
+		command.Subcommands = append(command.Subcommands, edit.Make(true))
+	}
+	return command
+}
+
+func printDiff(ctx *cli.Context, eh *editor.Hostfile) (err error) {
+	var diff string
+	if diff, err = eh.Diff(); err != nil {
+		return cli.Exit(fmt.Sprintf("error reading %v: %v", eh.Path, err), 1)
+	}
+	_, _ = fmt.Fprint(ctx.App.Writer, diff)
+	return
+}
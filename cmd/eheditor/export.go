@@ -0,0 +1,130 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+var formatFlag = &cli.StringFlag{
+	Name:    "format",
+	Usage:   "json or yaml, detected from the file extension when not given",
+	Aliases: []string{"F"},
+}
+
+func makeExportCommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:      "export",
+			Usage:     "write the host entries as json or yaml",
+			ArgsUsage: "[output file]",
+			Flags:     []cli.Flag{fileFlag, formatFlag},
+			Action:    exportAction,
+		},
+		importCommand.Make(false),
+	}
+}
+
+var importCommand = editCommand{
+	Name:      "import",
+	ArgsUsage: "[input file]",
+	Usage:     "replace the host entries with ones read from json or yaml",
+	Flags:     []cli.Flag{formatFlag},
+	Action:    importAction,
+}
+
+func exportFormat(ctx *cli.Context, path string) (format editor.ExportFormat, err error) {
+	value := ctx.String("format")
+	if value == "" {
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			value = string(editor.FormatYAML)
+		default:
+			value = string(editor.FormatJSON)
+		}
+	}
+	switch format = editor.ExportFormat(value); format {
+	case editor.FormatJSON, editor.FormatYAML:
+	default:
+		err = fmt.Errorf("unsupported format: %q", value)
+	}
+	return
+}
+
+func exportAction(ctx *cli.Context) (err error) {
+	output := ctx.Args().First()
+	var format editor.ExportFormat
+	if format, err = exportFormat(ctx, output); err != nil {
+		return cli.Exit(fmt.Sprintf("export: %v", err), 1)
+	}
+	var eh *editor.Hostfile
+	if eh, err = loadHostfile(ctx); err != nil {
+		return
+	}
+
+	var w io.Writer = ctx.App.Writer
+	if output != "" && output != "-" {
+		var fh *os.File
+		if fh, err = os.Create(output); err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+		defer func() { _ = fh.Close() }()
+		w = fh
+	}
+	if err = eh.Export(w, format); err != nil {
+		return cli.Exit(fmt.Sprintf("export: %v", err), 1)
+	}
+	return
+}
+
+func importAction(ctx *cli.Context, eh *editor.Hostfile) (changed bool, err error) {
+	input := ctx.Args().First()
+	var format editor.ExportFormat
+	if format, err = exportFormat(ctx, input); err != nil {
+		return
+	}
+
+	var r io.Reader = os.Stdin
+	if input != "" && input != "-" {
+		var fh *os.File
+		if fh, err = os.Open(input); err != nil {
+			return
+		}
+		defer func() { _ = fh.Close() }()
+		r = fh
+	}
+
+	before := eh.String()
+	var findings editor.Findings
+	if findings, err = eh.Import(r, format); err != nil {
+		return
+	}
+	// the entries imported may still have warnings, as check would report
+	for _, finding := range findings {
+		if finding.Severity >= editor.SeverityWarning {
+			result := checkResult{File: eh.Path, Line: finding.Line, Severity: finding.Severity.String(), Rule: finding.Rule, Message: finding.Message}
+			_, _ = fmt.Fprintln(ctx.App.ErrWriter, result.String())
+		}
+	}
+	changed = eh.String() != before
+	return
+}
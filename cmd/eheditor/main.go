@@ -15,6 +15,9 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 
 	"github.com/urfave/cli/v2"
@@ -22,6 +25,7 @@ import (
 	clcli "github.com/go-corelibs/cli"
 	"github.com/go-curses/cdk/log"
 
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
 	"github.com/go-curses/coreutils-etc-hosts-editor/ui"
 )
 
@@ -47,8 +51,17 @@ func main() {
 		"/dev/tty",
 	)
 	appCLI := ehe.App.CLI()
-	appCLI.UsageText = "eheditor [options] [/etc/hosts]"
+	appCLI.UsageText = "eheditor [options] [/etc/hosts]\n" +
+		"eheditor [options] command [command options] [arguments...]"
 	appCLI.HideHelpCommand = true
+	appCLI.Commands = append(makeCommands(), makeExportCommands()...)
+	appCLI.Commands = append(appCLI.Commands, blockCommand.Make(false), makeDiffCommand(), makeCheckCommand(), makeRefreshCommand(), makeConfigCommand())
+	appCLI.Before = func(ctx *cli.Context) (err error) {
+		if err = loadConfig(ctx); err == nil {
+			err = loadPolicy(ctx)
+		}
+		return
+	}
 	appCLI.EnableBashCompletion = true
 	appCLI.UseShortOptionHandling = true
 	ehe.App.AddFlag(&cli.BoolFlag{
@@ -56,6 +69,39 @@ func main() {
 		Usage:   "do not write any changes to the etc hosts file",
 		Aliases: []string{"r"},
 	})
+	ehe.App.AddFlag(&cli.IntFlag{
+		Name:    "backups",
+		Usage:   "number of timestamped backups to keep when saving",
+		Value:   3,
+		Aliases: []string{"b"},
+	})
+	ehe.App.AddFlag(&cli.StringFlag{
+		Name:  "sidebar",
+		Usage: "list the sidebar by domain, address or entry",
+		Value: "domain",
+	})
+	ehe.App.AddFlag(&cli.StringFlag{
+		Name:  "theme",
+		Usage: "background color of the editor, a color name or #rrggbb",
+		Value: "navy",
+	})
+	ehe.App.AddFlag(&cli.StringFlag{
+		Name:  "dns-server",
+		Usage: "host[:port] of the DNS server for nslookup entries, instead of the system resolver",
+	})
+	ehe.App.AddFlag(&cli.DurationFlag{
+		Name:  "dns-timeout",
+		Usage: "time limit for each nslookup",
+		Value: editor.DefaultLookupTimeout,
+	})
+	ehe.App.AddFlag(&cli.StringFlag{
+		Name:  "presets",
+		Usage: "directory of hosts files offered as presets when adding entries (default: ~/.config/eheditor/presets)",
+	})
+	ehe.App.AddFlag(&cli.StringFlag{
+		Name:  "policy",
+		Usage: "yaml or json file of the required, protected and grouped entries (default: ~/.config/eheditor/policy.yaml)",
+	})
 	cli.VersionFlag = &cli.BoolFlag{
 		Name:    "version",
 		Usage:   "display the version",
@@ -66,3 +112,22 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// loadPolicy replaces the editor.DefaultPolicy with the one in the --policy
+// file, or the default policy file if there is one
+func loadPolicy(ctx *cli.Context) (err error) {
+	path := ctx.String("policy")
+	if path == "" {
+		if path = editor.DefaultPolicyPath(); path == "" {
+			return
+		} else if _, err = os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	var policy *editor.Policy
+	if policy, err = editor.LoadPolicy(path); err != nil {
+		return cli.Exit(fmt.Sprintf("error loading policy: %v", err), 1)
+	}
+	editor.DefaultPolicy = policy
+	return
+}
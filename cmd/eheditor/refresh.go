@@ -0,0 +1,87 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+func makeRefreshCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "refresh",
+		Usage: "update the addresses of all entries with an #nslookup domain",
+		Flags: []cli.Flag{
+			fileFlag,
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Usage:   "report the changes without saving them",
+				Aliases: []string{"n"},
+			},
+			&cli.StringFlag{
+				Name:    "lookup",
+				Usage:   "first, lowest, ipv4 or ipv6 address for entries without a lookup policy",
+				Value:   string(editor.DefaultLookupPolicy),
+				Aliases: []string{"l"},
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Usage:   "number of lookups to perform concurrently",
+				Value:   8,
+				Aliases: []string{"j"},
+			},
+		},
+		Action: refreshAction,
+	}
+}
+
+func refreshAction(ctx *cli.Context) (err error) {
+	policy := editor.LookupPolicy(ctx.String("lookup"))
+	if policy == "" || !policy.Valid() {
+		return cli.Exit(fmt.Sprintf("refresh: unknown lookup policy: %q", policy), 1)
+	}
+	var eh *editor.Hostfile
+	if eh, err = loadHostfile(ctx); err != nil {
+		return
+	}
+
+	var changed, failed int
+	resolver := editor.NewResolver(ctx.String("dns-server"), ctx.Duration("dns-timeout"), editor.DefaultLookupTTL)
+	results := eh.Refresh(ctx.Context, resolver, policy, ctx.Int("jobs"))
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed += 1
+			_, _ = fmt.Fprintf(ctx.App.ErrWriter, "%v: %v\n", result.Lookup, result.Err)
+		case result.Changed():
+			changed += 1
+			_, _ = fmt.Fprintf(ctx.App.Writer, "%v: %v -> %v\n", result.Lookup, result.Previous, result.Address)
+		}
+	}
+	_, _ = fmt.Fprintf(ctx.App.Writer, "%d of %d lookup entries updated, %d failed\n", changed, len(results), failed)
+
+	if changed > 0 && !ctx.Bool("dry-run") {
+		if err = saveHostfile(ctx, eh); err != nil {
+			return
+		}
+	}
+	if failed > 0 {
+		return cli.Exit("", 1)
+	}
+	return
+}
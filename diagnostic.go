@@ -0,0 +1,103 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Severity uint8
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", s)
+}
+
+// Diagnostic describes a problem found with a specific line of a hosts file
+type Diagnostic struct {
+	// Line is the line number, starting from 1
+	Line int
+	// Column is the column number, starting from 1
+	Column int
+	// Severity indicates how serious the problem is
+	Severity Severity
+	// Text is the original line of text, without the newline
+	Text string
+	// Reason is a short description of the problem
+	Reason string
+}
+
+func newDiagnostic(severity Severity, line int, text, reason string, argv ...interface{}) Diagnostic {
+	column := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsSpace(r) }) + 1
+	if column < 1 {
+		column = 1
+	}
+	return Diagnostic{
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Text:     text,
+		Reason:   fmt.Sprintf(reason, argv...),
+	}
+}
+
+// String returns the diagnostic in "line:column: severity: reason" form
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %v: %v", d.Line, d.Column, d.Severity, d.Reason)
+}
+
+// Format returns the diagnostic in the compiler-style "path:line:column:
+// severity: reason" form
+func (d Diagnostic) Format(path string) string {
+	return path + ":" + d.String()
+}
+
+type Diagnostics []Diagnostic
+
+// Worst returns the highest Severity present, or SeverityInfo if there are
+// no diagnostics
+func (d Diagnostics) Worst() (worst Severity) {
+	for _, diagnostic := range d {
+		if diagnostic.Severity > worst {
+			worst = diagnostic.Severity
+		}
+	}
+	return
+}
+
+// Format returns all diagnostics, one per line, in the compiler-style form
+func (d Diagnostics) Format(path string) string {
+	var sb strings.Builder
+	for _, diagnostic := range d {
+		sb.WriteString(diagnostic.Format(path))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
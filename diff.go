@@ -0,0 +1,308 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines around each change in Diff
+const DiffContext = 3
+
+// Diff returns a unified diff between the file at Path and the content Save
+// would write, which is empty when there are no changes
+func (eh *Hostfile) Diff() (diff string, err error) {
+	var before []byte
+	if before, err = os.ReadFile(eh.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	return unifiedDiff(eh.Path, string(before), eh.String(), DiffContext), nil
+}
+
+type diffOp struct {
+	kind byte // ' ' for unchanged, '-' for removed and '+' for added lines
+	a, b int  // line indexes in the old and new content
+}
+
+func unifiedDiff(path, before, after string, context int) string {
+	if before == after {
+		return ""
+	}
+	a, b := diffSplit(before), diffSplit(after)
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	sb.WriteString("--- " + path + "\n")
+	sb.WriteString("+++ " + path + "\n")
+	for start, end := 0, 0; end < len(ops); {
+		// find the next change and extend the hunk while the following
+		// change is near enough for their context lines to overlap
+		first := end
+		for first < len(ops) && ops[first].kind == ' ' {
+			first += 1
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for idx := first; idx < len(ops) && idx-last <= 2*context; idx++ {
+			if ops[idx].kind != ' ' {
+				last = idx
+			}
+		}
+		start = first - context
+		if start < end {
+			start = end
+		}
+		if end = last + context + 1; end > len(ops) {
+			end = len(ops)
+		}
+		writeDiffHunk(&sb, a, b, ops[start:end])
+	}
+	return sb.String()
+}
+
+func writeDiffHunk(sb *strings.Builder, a, b []string, ops []diffOp) {
+	var aCount, bCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aCount += 1
+		}
+		if op.kind != '-' {
+			bCount += 1
+		}
+	}
+	sb.WriteString(fmt.Sprintf("@@ -%v +%v @@\n", diffRange(ops[0].a, aCount), diffRange(ops[0].b, bCount)))
+	for _, op := range ops {
+		var line string
+		if op.kind == '+' {
+			line = b[op.b]
+		} else {
+			line = a[op.a]
+		}
+		sb.WriteByte(op.kind)
+		sb.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func diffRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// diffSplit splits text into lines, each including its newline
+func diffSplit(text string) (lines []string) {
+	for text != "" {
+		idx := strings.IndexByte(text, '\n')
+		if idx < 0 {
+			return append(lines, text)
+		}
+		lines = append(lines, text[:idx+1])
+		text = text[idx+1:]
+	}
+	return
+}
+
+// diffLines returns the shortest edit script turning a into b, in order, with
+// the removed lines of each change before the added ones as in diff -u
+func diffLines(a, b []string) (ops []diffOp) {
+	// lines found in only one of a and b are never kept, leaving them out of
+	// the search keeps it fast when most of the lines changed
+	inA, inB := make(map[string]bool, len(a)), make(map[string]bool, len(b))
+	for _, line := range a {
+		inA[line] = true
+	}
+	for _, line := range b {
+		inB[line] = true
+	}
+	var aKept, bKept []int
+	var aLines, bLines []string
+	for idx, line := range a {
+		if inB[line] {
+			aKept, aLines = append(aKept, idx), append(aLines, line)
+		}
+	}
+	for idx, line := range b {
+		if inA[line] {
+			bKept, bLines = append(bKept, idx), append(bLines, line)
+		}
+	}
+
+	var x, y int
+	removeTo := func(end int) {
+		for ; x < end; x++ {
+			ops = append(ops, diffOp{kind: '-', a: x, b: y})
+		}
+	}
+	addTo := func(end int) {
+		for ; y < end; y++ {
+			ops = append(ops, diffOp{kind: '+', a: x, b: y})
+		}
+	}
+	for _, op := range myersDiff(aLines, bLines) {
+		switch op.kind {
+		case ' ':
+			removeTo(aKept[op.a])
+			addTo(bKept[op.b])
+			ops = append(ops, diffOp{kind: ' ', a: x, b: y})
+			x, y = x+1, y+1
+		case '-':
+			removeTo(aKept[op.a] + 1)
+		case '+':
+			addTo(bKept[op.b] + 1)
+		}
+	}
+	removeTo(len(a))
+	addTo(len(b))
+
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start += 1
+			continue
+		}
+		end, removed := start, 0
+		for ; end < len(ops) && ops[end].kind != ' '; end++ {
+			if ops[end].kind == '-' {
+				removed += 1
+			}
+		}
+		x, y := ops[start].a, ops[start].b
+		for idx := start; idx < end; idx++ {
+			if count := idx - start; count < removed {
+				ops[idx] = diffOp{kind: '-', a: x + count, b: y}
+			} else {
+				ops[idx] = diffOp{kind: '+', a: x + removed, b: y + count - removed}
+			}
+		}
+		start = end
+	}
+	return
+}
+
+// myersDiff is Eugene W. Myers' O(ND) difference algorithm, in its linear
+// space variant which splits the problem around the middle snake of the edit
+// script and solves each half the same way
+func myersDiff(a, b []string) (ops []diffOp) {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, diffOp{kind: ' ', a: aLo, b: bLo})
+		aLo, bLo = aLo+1, bLo+1
+	}
+	var suffix int
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix += 1
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.ops = append(d.ops, diffOp{kind: '+', a: aLo, b: y})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.ops = append(d.ops, diffOp{kind: '-', a: x, b: bLo})
+		}
+	default:
+		// with the common ends removed both halves have fewer edits
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, diffOp{kind: ' ', a: x, b: y})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for idx := 0; idx < suffix; idx++ {
+		d.ops = append(d.ops, diffOp{kind: ' ', a: aHi + idx, b: bHi + idx})
+	}
+}
+
+// middleSnake searches from both ends of a[aLo:aHi] and b[bLo:bHi] at once
+// and returns the start and end of the diagonal where the two searches meet
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	// forward[k] and backward[k] are the furthest x reached on diagonal k,
+	// counting from the start and from the end respectively
+	forward := make([]int, 2*limit+3)
+	backward := make([]int, 2*limit+3)
+
+	for depth := 0; depth <= limit; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var fx int
+			if k == -depth || (k != depth && forward[offset+k-1] < forward[offset+k+1]) {
+				fx = forward[offset+k+1]
+			} else {
+				fx = forward[offset+k-1] + 1
+			}
+			fy := fx - k
+			sx, sy := fx, fy
+			for fx < n && fy < m && d.a[aLo+fx] == d.b[bLo+fy] {
+				fx, fy = fx+1, fy+1
+			}
+			forward[offset+k] = fx
+			// the backward search on the same diagonal is at depth-1
+			if rk := delta - k; odd && rk >= -(depth-1) && rk <= depth-1 && fx+backward[offset+rk] >= n {
+				return aLo + sx, bLo + sy, aLo + fx, bLo + fy
+			}
+		}
+		for k := -depth; k <= depth; k += 2 {
+			var bx int
+			if k == -depth || (k != depth && backward[offset+k-1] < backward[offset+k+1]) {
+				bx = backward[offset+k+1]
+			} else {
+				bx = backward[offset+k-1] + 1
+			}
+			by := bx - k
+			sx, sy := bx, by
+			for bx < n && by < m && d.a[aHi-1-bx] == d.b[bHi-1-by] {
+				bx, by = bx+1, by+1
+			}
+			backward[offset+k] = bx
+			if fk := delta - k; !odd && fk >= -depth && fk <= depth && bx+forward[offset+fk] >= n {
+				return aHi - bx, bHi - by, aHi - sx, bHi - sy
+			}
+		}
+	}
+	// not reached, the searches meet by the time each has made half the edits
+	return aLo, bLo, aLo, bLo
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int) (text string) {
+		for idx := 1; idx <= n; idx++ {
+			text += strings.Repeat(string(rune('a'+idx-1)), 2) + "\n"
+		}
+		return
+	}
+	ten := lines(10)
+	for _, test := range []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{"unchanged", ten, ten, ""},
+		{"changed line", ten, strings.Replace(ten, "ee\n", "EE\n", 1), `@@ -2,7 +2,7 @@
+ bb
+ cc
+ dd
+-ee
++EE
+ ff
+ gg
+ hh
+`},
+		{"added first line", ten, "00\n" + ten, `@@ -1,3 +1,4 @@
++00
+ aa
+ bb
+ cc
+`},
+		{"removed last line", ten, lines(9), `@@ -7,4 +7,3 @@
+ gg
+ hh
+ ii
+-jj
+`},
+		{"separate hunks", ten, strings.NewReplacer("aa\n", "AA\n", "jj\n", "JJ\n").Replace(ten), `@@ -1,4 +1,4 @@
+-aa
++AA
+ bb
+ cc
+ dd
+@@ -7,4 +7,4 @@
+ gg
+ hh
+ ii
+-jj
++JJ
+`},
+		{"joined hunks", ten, strings.NewReplacer("bb\n", "BB\n", "hh\n", "HH\n").Replace(ten), `@@ -1,10 +1,10 @@
+ aa
+-bb
++BB
+ cc
+ dd
+ ee
+ ff
+ gg
+-hh
++HH
+ ii
+ jj
+`},
+		{"replaced lines", lines(3), "xx\nyy\n", `@@ -1,3 +1,2 @@
+-aa
+-bb
+-cc
++xx
++yy
+`},
+		{"new file", "", lines(2), `@@ -0,0 +1,2 @@
++aa
++bb
+`},
+		{"emptied file", lines(2), "", `@@ -1,2 +0,0 @@
+-aa
+-bb
+`},
+		{"added newline at end", "aa\nbb", "aa\nbb\n", `@@ -1,2 +1,2 @@
+ aa
+-bb
+\ No newline at end of file
++bb
+`},
+		{"removed newline at end", "aa\nbb\n", "aa\nbb", `@@ -1,2 +1,2 @@
+ aa
+-bb
++bb
+\ No newline at end of file
+`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.want != "" {
+				test.want = "--- hosts\n+++ hosts\n" + test.want
+			}
+			if got := unifiedDiff("hosts", test.before, test.after, DiffContext); got != test.want {
+				t.Errorf("unifiedDiff() =\n%v\nwant\n%v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesShortest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	lines := func() (lines []string) {
+		for count := random.Intn(12); count > 0; count-- {
+			lines = append(lines, string(rune('a'+random.Intn(4)))+"\n")
+		}
+		return
+	}
+	for round := 0; round < 2000; round++ {
+		a, b := lines(), lines()
+		ops := diffLines(a, b)
+
+		// the script must turn a into b, each change removing before adding
+		var before, after []string
+		var edits int
+		for idx, op := range ops {
+			switch op.kind {
+			case ' ':
+				if a[op.a] != b[op.b] {
+					t.Fatalf("diffLines(%q, %q) keeps %q as %q", a, b, a[op.a], b[op.b])
+				}
+				before, after = append(before, a[op.a]), append(after, b[op.b])
+			case '-':
+				before, edits = append(before, a[op.a]), edits+1
+			case '+':
+				after, edits = append(after, b[op.b]), edits+1
+				if idx+1 < len(ops) && ops[idx+1].kind == '-' {
+					t.Fatalf("diffLines(%q, %q) adds before removing", a, b)
+				}
+			}
+		}
+		if fmt.Sprint(before) != fmt.Sprint(a) || fmt.Sprint(after) != fmt.Sprint(b) {
+			t.Fatalf("diffLines(%q, %q) gives %q and %q", a, b, before, after)
+		}
+		// and be as short as the longest common subsequence allows
+		common := make([][]int, len(a)+1)
+		for i := range common {
+			common[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					common[i][j] = common[i+1][j+1] + 1
+				} else {
+					common[i][j] = max(common[i+1][j], common[i][j+1])
+				}
+			}
+		}
+		if want := len(a) + len(b) - 2*common[0][0]; edits != want {
+			t.Fatalf("diffLines(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	// the edit script of a blocklist import is as long as the blocklist
+	before := strings.Repeat("127.0.0.1 localhost\n", 10)
+	var blocked strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&blocked, "0.0.0.0 ads%d.example.com\n", i)
+	}
+	after := before[:100] + blocked.String() + before[100:]
+	diff := unifiedDiff("hosts", before, after, DiffContext)
+	if !strings.HasPrefix(diff, "--- hosts\n+++ hosts\n@@ -3,6 +3,20006 @@\n") {
+		t.Errorf("unifiedDiff() = %.100q", diff)
+	}
+
+	// and of replacing every line as long as both files
+	var replaced strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&replaced, "0.0.0.0 other%d.example.com\n", i)
+	}
+	if diff = unifiedDiff("hosts", blocked.String(), replaced.String(), DiffContext); strings.Count(diff, "\n") != 40003 {
+		t.Errorf("unifiedDiff() has %d lines, want 40003", strings.Count(diff, "\n"))
+	}
+}
+
+func TestHostfileDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	content := "127.0.0.1 localhost\n10.0.0.1 one.lan\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eh := mustParse(t, content)
+	eh.Path = path
+	if diff, err := eh.Diff(); err != nil || diff != "" {
+		t.Errorf("unchanged Diff() = %q, %v", diff, err)
+	}
+	hostWithDomain(t, eh, "one.lan").SetAddress("10.0.0.2")
+	want := "--- " + path + "\n+++ " + path + "\n@@ -1,2 +1,2 @@\n 127.0.0.1 localhost\n-10.0.0.1 one.lan\n+10.0.0.2 one.lan\n"
+	if diff, err := eh.Diff(); err != nil || diff != want {
+		t.Errorf("Diff() = %q, %v, want %q", diff, err, want)
+	}
+
+	eh.Path = filepath.Join(t.TempDir(), "missing")
+	want = "--- " + eh.Path + "\n+++ " + eh.Path + "\n@@ -0,0 +1,2 @@\n+127.0.0.1 localhost\n+10.0.0.2 one.lan\n"
+	if diff, err := eh.Diff(); err != nil || diff != want {
+		t.Errorf("Diff() of a missing file = %q, %v, want %q", diff, err, want)
+	}
+}
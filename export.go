@@ -0,0 +1,315 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+
+	cstrings "github.com/go-curses/cdk/lib/strings"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportVersion is the version of the Document schema
+const ExportVersion = 1
+
+type ExportFormat string
+
+const (
+	FormatJSON ExportFormat = "json"
+	FormatYAML ExportFormat = "yaml"
+)
+
+// Document is the serialized form of a Hostfile:
+//
+//	version: 1
+//	hosts:
+//	  - order: 1                 # position in the hosts file, starting at 1
+//	    address: 127.0.0.1       # IP address, empty for comment entries
+//	    domains: [localhost]     # domain names for the address
+//	    active: true             # false when the host line is commented out,
+//	                             # not given for comment entries
+//	    lookup: example.com      # domain to nslookup the address from
+//	    policy: ipv4             # how to choose among the lookup addresses
+//	    comment: "some text"     # comment lines preceding the host line
+//	    trailing: "more text"    # comment at the end of the host line
+//
+// Entries with only a comment (no address, domains or lookup) are comment
+// entries
+type Document struct {
+	Version int     `json:"version" yaml:"version"`
+	Hosts   []Entry `json:"hosts" yaml:"hosts"`
+}
+
+// Entry is the serialized form of a Host, see Document
+type Entry struct {
+	Order    int      `json:"order" yaml:"order"`
+	Address  string   `json:"address,omitempty" yaml:"address,omitempty"`
+	Domains  []string `json:"domains,omitempty" yaml:"domains,omitempty"`
+	Active   bool     `json:"active" yaml:"active"`
+	Lookup   string   `json:"lookup,omitempty" yaml:"lookup,omitempty"`
+	Policy   string   `json:"policy,omitempty" yaml:"policy,omitempty"`
+	Comment  string   `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Trailing string   `json:"trailing,omitempty" yaml:"trailing,omitempty"`
+}
+
+// IsComment reports whether the entry is a comment entry
+func (e Entry) IsComment() bool {
+	return e.Address == "" && e.Lookup == "" && len(e.Domains) == 0
+}
+
+// MarshalJSON leaves out Active for comment entries, which have no host line
+// to comment out
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type entry Entry
+	if e.IsComment() {
+		return json.Marshal(struct {
+			entry
+			Active bool `json:"active,omitempty"`
+		}{entry: entry(e)})
+	}
+	return json.Marshal(entry(e))
+}
+
+// MarshalYAML is MarshalJSON for yaml
+func (e Entry) MarshalYAML() (value interface{}, err error) {
+	type entry Entry
+	node := &yaml.Node{}
+	if err = node.Encode(entry(e)); err != nil || !e.IsComment() {
+		return node, err
+	}
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == "active" {
+			node.Content = append(node.Content[:idx], node.Content[idx+2:]...)
+			break
+		}
+	}
+	return node, nil
+}
+
+// check reports the problems with the entry which ValidationRules are errors
+// for active entries and only warnings for inactive ones, as hosts files
+// often have notes that look like host lines. An address which is not an IP
+// address would be written as 0.0.0.0, while an entry with a lookup may have
+// no address until the lookup is refreshed
+func (e Entry) check() (err error) {
+	if e.Lookup != "" && e.Address == "" {
+		return
+	}
+	if !e.IsComment() && !e.Active && net.ParseIP(e.Address) == nil {
+		err = fmt.Errorf("%q is not a valid IP address", e.Address)
+	}
+	return
+}
+
+func (e Entry) sameContent(other Entry) bool {
+	return e.Address == other.Address &&
+		e.Active == other.Active &&
+		e.Lookup == other.Lookup &&
+		e.Policy == other.Policy &&
+		e.Comment == other.Comment &&
+		e.Trailing == other.Trailing &&
+		cstrings.EqualStringSlices(e.Domains, other.Domains)
+}
+
+// Entry returns the serialized form of the host, without an Order
+func (h *Host) Entry() Entry {
+	h.RLock()
+	defer h.RUnlock()
+	if h.onlyComment {
+		return Entry{Comment: h.comment}
+	}
+	return Entry{
+		Address:  h.address,
+		Domains:  append([]string{}, h.domains...),
+		Active:   h.active,
+		Lookup:   h.lookup,
+		Policy:   string(h.policy),
+		Comment:  h.comment,
+		Trailing: h.trailing,
+	}
+}
+
+// NewHostFromEntry creates a new Host from the serialized form
+func NewHostFromEntry(e Entry) (host *Host) {
+	if e.IsComment() {
+		return NewComment(e.Comment)
+	}
+	return NewHostFromInfo(HostInfo{
+		active:   e.Active,
+		lookup:   e.Lookup,
+		policy:   LookupPolicy(e.Policy),
+		address:  e.Address,
+		comment:  e.Comment,
+		domains:  append([]string{}, e.Domains...),
+		trailing: e.Trailing,
+	})
+}
+
+// SetEntry replaces the content of the host with the serialized form, the
+// Order is ignored. Only the comment is used for comment hosts
+func (h *Host) SetEntry(e Entry) {
+	h.Lock()
+	address, domains := h.address, h.domains
+	defer h.reindex(address, domains)
+	defer h.Unlock()
+	h.comment = e.Comment
+	if h.onlyComment {
+		return
+	}
+	h.active = e.Active
+	h.lookup = e.Lookup
+	h.policy = LookupPolicy(e.Policy)
+	h.address = e.Address
+	h.domains = append([]string{}, e.Domains...)
+	h.trailing = e.Trailing
+}
+
+// Entries returns the serialized form of all hosts, in order
+func (eh *Hostfile) Entries() (entries []Entry) {
+	for idx, host := range eh.Hosts() {
+		entry := host.Entry()
+		entry.Order = idx + 1
+		entries = append(entries, entry)
+	}
+	return
+}
+
+// SetEntries replaces all hosts with the given entries, sorted by Order.
+// Existing hosts with the same content are kept, along with their original
+// layout, instead of being replaced with new ones. The entries are checked
+// with the ValidationRules first and nothing is changed when any have errors,
+// otherwise the findings of Validate are returned
+func (eh *Hostfile) SetEntries(entries []Entry) (findings Findings, err error) {
+	sorted := append([]Entry{}, entries...)
+	for idx := range sorted {
+		if sorted[idx].Order <= 0 {
+			sorted[idx].Order = idx + 1
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	if err = checkEntries(sorted); err != nil {
+		return
+	}
+
+	existing := append([]*Host{}, eh.Hosts()...)
+	hosts := make([]*Host, len(sorted))
+	for idx, entry := range sorted {
+		for jdx, h := range existing {
+			if h != nil && h.Entry().sameContent(entry) {
+				hosts[idx], existing[jdx] = h, nil
+				break
+			}
+		}
+	}
+	for idx, entry := range sorted {
+		if hosts[idx] != nil {
+			continue
+		}
+		// a modified entry keeps the layout of the host it replaces
+		if at := entry.Order - 1; at >= 0 && at < len(existing) {
+			if h := existing[at]; h != nil && h.IsOnlyComment() == entry.IsComment() {
+				h.SetEntry(entry)
+				hosts[idx], existing[at] = h, nil
+				continue
+			}
+		}
+		hosts[idx] = NewHostFromEntry(entry)
+	}
+
+	eh.Lock()
+	eh.setHosts(hosts)
+	eh.Unlock()
+	return eh.Validate(), nil
+}
+
+// checkEntries returns an error describing each of the entries, in order,
+// with problems that are errors
+func checkEntries(sorted []Entry) (err error) {
+	hosts := make([]*Host, len(sorted))
+	positions := make(map[*Host]int, len(sorted))
+	problems := make([][]string, len(sorted))
+	for idx, entry := range sorted {
+		if err = entry.check(); err != nil {
+			problems[idx] = append(problems[idx], err.Error())
+		}
+		hosts[idx] = NewHostFromEntry(entry)
+		positions[hosts[idx]] = idx
+	}
+	for _, rule := range ValidationRules {
+		for _, finding := range rule.Check(hosts) {
+			if finding.Severity == SeverityError && finding.Host != nil {
+				idx := positions[finding.Host]
+				problems[idx] = append(problems[idx], fmt.Sprintf("%v (%v)", finding.Message, rule.Name))
+			}
+		}
+	}
+	var errs []error
+	for idx, messages := range problems {
+		for _, message := range messages {
+			errs = append(errs, fmt.Errorf("entry %d: %v", sorted[idx].Order, message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Export writes the serialized form of all hosts to w
+func (eh *Hostfile) Export(w io.Writer, format ExportFormat) (err error) {
+	document := Document{Version: ExportVersion, Hosts: eh.Entries()}
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(document)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(document); err == nil {
+			err = encoder.Close()
+		}
+	default:
+		err = fmt.Errorf("unsupported export format: %q", format)
+	}
+	return
+}
+
+// Import reads the serialized form of hosts from r and replaces all hosts
+// with them, see SetEntries
+func (eh *Hostfile) Import(r io.Reader, format ExportFormat) (findings Findings, err error) {
+	var document Document
+	switch format {
+	case FormatJSON:
+		err = json.NewDecoder(r).Decode(&document)
+	case FormatYAML:
+		if err = yaml.NewDecoder(r).Decode(&document); errors.Is(err, io.EOF) {
+			err = fmt.Errorf("empty yaml document")
+		}
+	default:
+		err = fmt.Errorf("unsupported import format: %q", format)
+	}
+	if err != nil {
+		return
+	}
+	if document.Version != ExportVersion {
+		return nil, fmt.Errorf("unsupported document version: %d", document.Version)
+	}
+	return eh.SetEntries(document.Hosts)
+}
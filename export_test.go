@@ -0,0 +1,165 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const exportContent = `# the local host
+127.0.0.1	localhost
+::1	localhost ip6-localhost
+
+###
+# a comment entry
+###
+
+#10.0.0.1 disabled.lan # not now
+#nslookup example.com ipv4
+93.184.216.34 example.com www.example.com
+`
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []ExportFormat{FormatJSON, FormatYAML} {
+		t.Run(string(format), func(t *testing.T) {
+			eh := mustParse(t, exportContent)
+			var buffer bytes.Buffer
+			if err := eh.Export(&buffer, format); err != nil {
+				t.Fatal(err)
+			}
+			exported := buffer.String()
+
+			// importing into the same file changes nothing
+			if _, err := eh.Import(strings.NewReader(exported), format); err != nil {
+				t.Fatal(err)
+			} else if got := eh.String(); got != exportContent {
+				t.Errorf("after import String() = %q, want %q", got, exportContent)
+			}
+
+			// importing into another file gives the same entries
+			other := mustParse(t, "10.9.9.9 other\n")
+			if _, err := other.Import(strings.NewReader(exported), format); err != nil {
+				t.Fatal(err)
+			} else if got, want := other.Entries(), eh.Entries(); !reflect.DeepEqual(got, want) {
+				t.Errorf("imported entries %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestExportCommentEntries(t *testing.T) {
+	eh := mustParse(t, "###\n# only a comment\n###\n")
+	for _, format := range []ExportFormat{FormatJSON, FormatYAML} {
+		var buffer bytes.Buffer
+		if err := eh.Export(&buffer, format); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buffer.String(), "active") {
+			t.Errorf("%v export of a comment entry has active:\n%v", format, buffer.String())
+		}
+	}
+	data, err := json.Marshal(Entry{Order: 1, Address: "10.0.0.1", Domains: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), `"active":false`) {
+		t.Errorf("inactive host entry marshalled without active: %s", data)
+	}
+}
+
+func TestImportRejectsInvalidEntries(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{"space in domain", Entry{Address: "10.0.0.1", Domains: []string{"my host"}, Active: true}, `"my host" cannot be written as a domain name`},
+		{"comment marker in domain", Entry{Address: "10.0.0.1", Domains: []string{"a#b"}, Active: true}, `"a#b" cannot be written as a domain name`},
+		{"comment marker in lookup", Entry{Address: "10.0.0.1", Domains: []string{"a"}, Lookup: "a#b", Active: true}, `"a#b" cannot be written as a lookup domain`},
+		{"invalid address", Entry{Address: "10.0.0", Domains: []string{"a"}, Active: true}, `"10.0.0" is not a valid IP address`},
+		{"invalid inactive address", Entry{Address: "10.0.0", Domains: []string{"a"}}, `"10.0.0" is not a valid IP address`},
+		{"missing address", Entry{Domains: []string{"a"}, Active: true}, "host entry has no address"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, exportContent)
+			entries := append(eh.Entries(), test.entry)
+			_, err := eh.SetEntries(entries)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("SetEntries error = %v, want %q", err, test.want)
+			} else if !strings.HasPrefix(err.Error(), "entry 7: ") {
+				t.Errorf("SetEntries error = %v, want it for entry 7", err)
+			}
+			if got := eh.String(); got != exportContent {
+				t.Errorf("rejected entries changed the hosts to %q", got)
+			}
+		})
+	}
+}
+
+func TestImportReportsWarnings(t *testing.T) {
+	eh := mustParse(t, exportContent)
+	entries := append(eh.Entries(), Entry{Address: "10.0.0.2", Domains: []string{"bad_name"}, Active: true})
+	findings, err := eh.SetEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, finding := range findings {
+		found = found || finding.Rule == "hostname"
+	}
+	if !found {
+		t.Errorf("no hostname warning in %v", findings)
+	}
+}
+
+func TestImportInactiveLookupEntry(t *testing.T) {
+	eh := mustParse(t, exportContent)
+	entry := Entry{Domains: []string{"example"}, Lookup: "example.com"}
+	if _, err := eh.SetEntries(append(eh.Entries(), entry)); err != nil {
+		t.Fatal(err)
+	}
+	if hosts := eh.HostsForDomain("example"); len(hosts) != 1 || hosts[0].Lookup() != "example.com" || hosts[0].Active() {
+		t.Errorf("SetEntries gave the hosts %v, want an inactive lookup of example.com", hosts)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		format  ExportFormat
+		content string
+		want    string
+	}{
+		{"json syntax", FormatJSON, `{"version": 1, "hosts": [}`, "invalid character"},
+		{"json version", FormatJSON, `{"version": 2, "hosts": []}`, "unsupported document version: 2"},
+		{"json type", FormatJSON, `{"version": 1, "hosts": [{"domains": "a"}]}`, "cannot unmarshal"},
+		{"yaml version", FormatYAML, "version: 0\nhosts: []\n", "unsupported document version: 0"},
+		{"yaml empty", FormatYAML, "# nothing\n", "empty yaml document"},
+		{"format", ExportFormat("toml"), "", `unsupported import format: "toml"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, exportContent)
+			if _, err := eh.Import(strings.NewReader(test.content), test.format); err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Import error = %v, want %q", err, test.want)
+			}
+			if got := eh.String(); got != exportContent {
+				t.Errorf("failed import changed the hosts to %q", got)
+			}
+		})
+	}
+}
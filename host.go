@@ -15,15 +15,18 @@
 package editor
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strings"
+	"sync/atomic"
 
 	"github.com/go-curses/cdk/lib/paint"
 	cstrings "github.com/go-curses/cdk/lib/strings"
 	"github.com/go-curses/cdk/lib/sync"
 )
 
+// HostImportance is the name of the Policy group of a host, see Importance
 type HostImportance string
 
 const (
@@ -32,12 +35,25 @@ const (
 	HostIsLocalhostIPv6 HostImportance = "ipv6"
 )
 
+// HostID identifies a Host for as long as the program runs, no two hosts
+// created with NewHost, NewHostFromInfo or NewComment (including the ones
+// parsed) have the same ID
+type HostID uint64
+
+var lastHostID atomic.Uint64
+
+func nextHostID() HostID {
+	return HostID(lastHostID.Add(1))
+}
+
 type HostInfo struct {
-	active  bool
-	lookup  string
-	address string
-	comment string
-	domains []string
+	active   bool
+	lookup   string
+	address  string
+	comment  string
+	domains  []string
+	trailing string
+	policy   LookupPolicy
 }
 
 func (h HostInfo) SameHostInfo(other HostInfo) (same bool) {
@@ -45,6 +61,8 @@ func (h HostInfo) SameHostInfo(other HostInfo) (same bool) {
 		h.lookup == other.lookup &&
 		h.address == other.address &&
 		h.comment == other.comment &&
+		h.trailing == other.trailing &&
+		h.policy == other.policy &&
 		cstrings.EqualStringSlices(h.domains, other.domains)
 	return
 }
@@ -52,17 +70,33 @@ func (h HostInfo) SameHostInfo(other HostInfo) (same bool) {
 type Host struct {
 	HostInfo
 
+	id HostID
+
 	original    HostInfo
 	onlyComment bool
 
-	cache []net.IP
+	format  lineFormat
+	leading []string
+	source  []string
+
+	findings Findings
+	// grouped new entries are written without a blank line separating them
+	// from the previous entry
+	grouped bool
+	// owner is the Hostfile the host was last added to, which is told when
+	// the address or domains change
+	owner *Hostfile
+	// moved is the trivia moved off the host when it was last removed
+	moved *trivia
 
 	sync.RWMutex
 }
 
 func NewComment(comment string) (host *Host) {
 	host = new(Host)
+	host.id = nextHostID()
 	host.onlyComment = true
+	host.format = defaultLineFormat
 	host.active = false
 	host.address = ""
 	host.lookup = ""
@@ -80,16 +114,29 @@ func NewComment(comment string) (host *Host) {
 
 func NewHostFromInfo(info HostInfo) (host *Host) {
 	host = new(Host)
+	host.id = nextHostID()
 	host.onlyComment = false
+	host.format = defaultLineFormat
 	host.active = info.active
 	host.address = info.address
 	host.lookup = info.lookup
 	host.comment = info.comment
 	host.domains = info.domains
+	host.trailing = info.trailing
+	host.policy = info.policy
 	host.original = info
 	return
 }
 
+// NewHost creates a new active host entry for the address and domains given
+func NewHost(address string, domains ...string) (host *Host) {
+	return NewHostFromInfo(HostInfo{
+		active:  true,
+		address: address,
+		domains: domains,
+	})
+}
+
 func (h *Host) Name() (ipOrName string) {
 	h.RLock()
 	defer h.RUnlock()
@@ -105,6 +152,13 @@ func (h *Host) String() string {
 	return h.address
 }
 
+// ID returns the identity of the host, which never changes
+func (h *Host) ID() HostID {
+	return h.id
+}
+
+// Equals reports whether the host has the same content as the other, which
+// may be a different host entirely, see ID
 func (h *Host) Equals(host *Host) bool {
 	h.RLock()
 	host.RLock()
@@ -123,6 +177,7 @@ func (h *Host) Equals(host *Host) bool {
 	return hIsOnlyComment == hostIsOnlyComment &&
 		h.address == host.address &&
 		h.comment == host.comment &&
+		h.trailing == host.trailing &&
 		h.active == host.active &&
 		cstrings.EqualStringSlices(h.domains, host.domains)
 }
@@ -133,6 +188,19 @@ func (h *Host) IsComment() bool {
 	return h.onlyComment
 }
 
+// parsed reports whether this entry was read from a hosts file
+func (h *Host) parsed() bool {
+	h.RLock()
+	defer h.RUnlock()
+	return h.source != nil
+}
+
+func (h *Host) isGrouped() bool {
+	h.RLock()
+	defer h.RUnlock()
+	return h.grouped
+}
+
 func (h *Host) Changed() bool {
 	h.RLock()
 	defer h.RUnlock()
@@ -142,9 +210,14 @@ func (h *Host) Changed() bool {
 func (h *Host) Line() string {
 	h.RLock()
 	defer h.RUnlock()
+	return h.line()
+}
+
+// line is Line without locking, the caller must hold at least the read lock
+func (h *Host) line() string {
 	var active string
 	if !h.active {
-		active = "#"
+		active = h.format.marker
 	}
 	var address string
 	if h.address == "" || !cstrings.StringIsIP(h.address) {
@@ -152,7 +225,16 @@ func (h *Host) Line() string {
 	} else {
 		address = h.address
 	}
-	return fmt.Sprintf("%v%v\t%v\n", active, address, strings.Join(h.domains, " "))
+	var trailing string
+	if h.trailing != "" {
+		trailing = h.format.inline + h.trailing
+	}
+	return fmt.Sprintf(
+		"%v%v%v%v%v%v\n",
+		h.format.indent, active, address,
+		h.format.separator, strings.Join(h.domains, h.format.spacing),
+		trailing,
+	)
 }
 
 func (h *Host) Empty() bool {
@@ -160,6 +242,7 @@ func (h *Host) Empty() bool {
 		return h.comment == ""
 	}
 	return h.comment == "" &&
+		h.trailing == "" &&
 		h.address == "" &&
 		h.lookup == "" &&
 		len(h.domains) == 0
@@ -169,25 +252,33 @@ func (h *Host) Block() string {
 	if h.Empty() {
 		return ""
 	}
-	var out string
+	var out strings.Builder
 
 	isComment := h.IsOnlyComment()
 	h.RLock()
 	defer h.RUnlock()
 
-	if isComment {
-		out += "###\n"
+	writeComment := func() {
 		for _, line := range rxNewlines.Split(h.comment, -1) {
-			out += "# " + line + "\n"
+			out.WriteString(h.format.comment)
+			out.WriteString(line)
+			out.WriteString("\n")
 		}
-		out += "###\n"
-		return out
 	}
 
-	if h.comment != "" {
-		for _, line := range rxNewlines.Split(h.comment, -1) {
-			out += "# " + line + "\n"
+	if isComment {
+		if h.format.banner {
+			out.WriteString("###\n")
 		}
+		writeComment()
+		if h.format.banner {
+			out.WriteString("###\n")
+		}
+		return out.String()
+	}
+
+	if h.comment != "" {
+		writeComment()
 	}
 
 	var lookup string
@@ -196,36 +287,72 @@ func (h *Host) Block() string {
 	} else {
 		lookup = h.lookup
 	}
-	if lookup != "" {
-		out += fmt.Sprintf("#nslookup %v\n", h.lookup)
+	if lookup != "" && h.policy != "" {
+		_, _ = fmt.Fprintf(&out, "#nslookup %v %v\n", lookup, h.policy)
+	} else if lookup != "" {
+		_, _ = fmt.Fprintf(&out, "#nslookup %v\n", lookup)
 	}
 
-	out += h.Line()
-	return out
+	out.WriteString(h.line())
+	return out.String()
 }
 
-func (h *Host) PerformLookup() (found []net.IP, err error) {
-	if len(h.cache) > 0 {
-		found = h.cache
-		return
+// Lines returns the lines of text this entry contributes to the hosts file,
+// without newlines. Entries which have not been modified since parsing are
+// returned exactly as they were read, including any blank or unrecognized
+// lines that preceded them
+func (h *Host) Lines() (lines []string) {
+	return h.linesEndingWith("")
+}
+
+// linesEndingWith is like Lines with end appended to each line written from
+// the entry, as the lines read are returned as they were
+func (h *Host) linesEndingWith(end string) (lines []string) {
+	h.RLock()
+	lines = append(lines, h.leading...)
+	source := h.source
+	changed := !h.SameHostInfo(h.original)
+	h.RUnlock()
+	if source != nil && !changed {
+		return append(lines, source...)
 	}
-	if lookup := h.Lookup(); lookup != "" {
-		if found, err = net.LookupIP(lookup); err == nil {
-			h.Lock()
-			h.cache = found
-			h.Unlock()
+	if block := h.Block(); block != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(block, "\n"), "\n") {
+			lines = append(lines, line+end)
 		}
+	}
+	return
+}
+
+// PerformLookup looks up the addresses of the lookup domain with the
+// DefaultResolver
+func (h *Host) PerformLookup() (found []net.IP, err error) {
+	return h.LookupWith(context.Background(), DefaultResolver)
+}
+
+// LookupWith looks up the addresses of the lookup domain with the resolver
+// given
+func (h *Host) LookupWith(ctx context.Context, resolver Resolver) (found []net.IP, err error) {
+	if lookup := h.Lookup(); lookup != "" {
+		found, err = resolver.LookupIP(ctx, lookup)
 	} else {
 		err = fmt.Errorf("missing domain to lookup")
 	}
 	return
 }
 
+// GetActualInfo describes the address of the host for display, comparing it
+// with the addresses found for the lookup domain with the DefaultResolver
 func (h *Host) GetActualInfo() (label, tooltip string) {
+	return h.GetActualInfoWith(context.Background(), DefaultResolver)
+}
+
+// GetActualInfoWith is GetActualInfo with the resolver given
+func (h *Host) GetActualInfoWith(ctx context.Context, resolver Resolver) (label, tooltip string) {
 	lookup := h.Lookup()
 	address := h.Address()
 	if cstrings.StringIsDomainName(lookup) {
-		if found, err := h.PerformLookup(); err != nil {
+		if found, err := h.LookupWith(ctx, resolver); err != nil {
 			label = fmt.Sprintf("%v (!)", address)
 			tooltip = err.Error()
 			return
@@ -261,7 +388,9 @@ func (h *Host) GetActualInfo() (label, tooltip string) {
 func (h *Host) SetActive(active bool) {
 	h.Lock()
 	h.active = active
+	address, domains := h.address, h.domains
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) Active() bool {
@@ -273,7 +402,9 @@ func (h *Host) Active() bool {
 func (h *Host) SetLookup(value string) {
 	h.Lock()
 	h.lookup = value
+	address, domains := h.address, h.domains
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) Lookup() string {
@@ -282,10 +413,29 @@ func (h *Host) Lookup() string {
 	return h.lookup
 }
 
+// SetLookupPolicy sets how Refresh chooses among the addresses found for the
+// lookup domain, empty for the default
+func (h *Host) SetLookupPolicy(policy LookupPolicy) {
+	h.Lock()
+	h.policy = policy
+	address, domains := h.address, h.domains
+	h.Unlock()
+	h.reindex(address, domains)
+}
+
+func (h *Host) LookupPolicy() LookupPolicy {
+	h.RLock()
+	defer h.RUnlock()
+	return h.policy
+}
+
 func (h *Host) SetAddress(value string) {
 	h.Lock()
+	address := h.address
 	h.address = value
+	domains := h.domains
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) Address() string {
@@ -297,7 +447,9 @@ func (h *Host) Address() string {
 func (h *Host) SetComment(text string) {
 	h.Lock()
 	h.comment = strings.TrimSpace(text)
+	address, domains := h.address, h.domains
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) Comment() string {
@@ -306,6 +458,24 @@ func (h *Host) Comment() string {
 	return h.comment
 }
 
+// SetTrailingComment updates the comment written after the domains on the
+// same line as the address
+func (h *Host) SetTrailingComment(text string) {
+	h.Lock()
+	h.trailing = strings.TrimSpace(rxNewlines.ReplaceAllString(text, " "))
+	address, domains := h.address, h.domains
+	h.Unlock()
+	h.reindex(address, domains)
+}
+
+// TrailingComment returns the comment written after the domains on the same
+// line as the address
+func (h *Host) TrailingComment() string {
+	h.RLock()
+	defer h.RUnlock()
+	return h.trailing
+}
+
 func (h *Host) IsOnlyComment() (onlyComment bool) {
 	h.RLock()
 	defer h.RUnlock()
@@ -319,13 +489,18 @@ func (h *Host) AppendComment(text string) {
 		h.comment += "\n"
 	}
 	h.comment += strings.TrimSpace(text)
+	address, domains := h.address, h.domains
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) SetDomains(text string) {
 	h.Lock()
+	domains := h.domains
 	h.domains = rxSpaceSep.Split(text, -1)
+	address := h.address
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) Domains() []string {
@@ -336,41 +511,73 @@ func (h *Host) Domains() []string {
 
 func (h *Host) AddDomain(domain string) {
 	h.Lock()
+	address, domains := h.address, h.domains
 	h.domains = append(h.domains, domain)
 	h.Unlock()
+	h.reindex(address, domains)
 }
 
 func (h *Host) RemoveDomain(domain string) {
 	h.Lock()
+	address, previous := h.address, h.domains
 	var domains []string
 	for _, existing := range h.domains {
 		if existing != domain {
-			domains = append(domains, domain)
+			domains = append(domains, existing)
 		}
 	}
 	h.domains = domains
 	h.Unlock()
+	h.reindex(address, previous)
+}
+
+// reindex updates the index of the Hostfile the host belongs to, after the
+// address or domains changed from those given or after other changes which
+// need the host to be validated again
+func (h *Host) reindex(address string, domains []string) {
+	h.RLock()
+	owner := h.owner
+	h.RUnlock()
+	if owner != nil {
+		owner.updateIndex(h, address, domains)
+	}
 }
 
+// HasDomain reports whether the host has the domain, ignoring case
 func (h *Host) HasDomain(needle string) (found bool) {
 	h.RLock()
 	defer h.RUnlock()
 	for _, domain := range h.domains {
-		if domain == needle {
+		if strings.EqualFold(domain, needle) {
 			return true
 		}
 	}
 	return
 }
 
+// Findings returns the problems found with this host by the last call to
+// Hostfile.Validate
+func (h *Host) Findings() Findings {
+	h.RLock()
+	defer h.RUnlock()
+	return h.findings
+}
+
+// Importance returns the name of the DefaultPolicy group of the host, or
+// HostNotImportant when it has none
 func (h *Host) Importance() HostImportance {
-	for _, domain := range h.domains {
-		switch domain {
-		case "localhost":
-			return HostIsLocalhostIPv4
-		case "ip6-allrouters", "ip6-allnodes", "ip6-loopback", "ip6-localhost":
-			return HostIsLocalhostIPv6
-		}
+	h.RLock()
+	defer h.RUnlock()
+	if h.onlyComment {
+		return HostNotImportant
 	}
-	return HostNotImportant
+	return DefaultPolicy.importance(h.domains)
+}
+
+// IsProtected reports whether the DefaultPolicy protects the host from being
+// removed, deactivated or given another address
+func (h *Host) IsProtected() bool {
+	h.RLock()
+	defer h.RUnlock()
+	return !h.onlyComment && DefaultPolicy.protects(h.domains)
 }
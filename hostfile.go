@@ -15,27 +15,66 @@
 package editor
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	cpaths "github.com/go-curses/cdk/lib/paths"
+	cstrings "github.com/go-curses/cdk/lib/strings"
 	"github.com/go-curses/cdk/lib/sync"
 	"github.com/go-curses/cdk/log"
 )
 
 var (
-	HostValidations = map[string]string{
-		"localhost":      "IPv4 (localhost)",
-		"ip6-localhost":  "IPv6 (localhost)",
-		"ip6-loopback":   "IPv6 (loopback)",
-		"ip6-allnodes":   "IPv6 (all nodes)",
-		"ip6-allrouters": "IPv6 (all routers)",
-	}
+	ErrModified = errors.New("modified by another program since it was read")
 )
 
+// DefaultHostsFile is the hosts file used when no other is given
+var DefaultHostsFile = "/etc/hosts"
+
 type Hostfile struct {
 	Path    string
 	hosts   []*Host
 	Comment string
+	// Backups is the number of timestamped backups to keep when saving, zero
+	// disables making backups
+	Backups int
+	// InPlace allows Save to rewrite the file in place, which is not atomic,
+	// when it cannot be replaced (such as a bind mounted file), instead of
+	// returning an error wrapping ErrNotAtomic
+	InPlace bool
+
+	header         []string
+	trailing       []string
+	noFinalNewline bool
+	// lineEnd is "\r" when most lines were read with CRLF line endings, and
+	// is written before the newline of each new or edited line
+	lineEnd string
+
+	// parsed are the hosts in the order they were originally parsed
+	parsed []*Host
+	// loaded is the path the hosts were parsed from, if any
+	loaded   string
+	modTime  time.Time
+	checksum [sha256.Size]byte
+
+	// index is built when needed by getIndex and cleared by clearIndex
+	index *hostIndex
+	// changed are the hosts which may have different findings since the last
+	// Validate, known only while validated is true, and lines are where the
+	// hosts were written at the last Validate
+	changed   map[*Host]bool
+	validated bool
+	lines     map[*Host]lineSpan
+	// indexLock guards the index and the changes to validate
+	indexLock sync.Mutex
 
 	sync.RWMutex
 }
@@ -46,35 +85,187 @@ func (eh *Hostfile) Hosts() []*Host {
 	return eh.hosts
 }
 
-func (eh *Hostfile) Save() (err error) {
-	if cpaths.FileWritable(eh.Path) {
-		content := eheditorFileHeading + "\n"
-		eh.RLock()
-		defer eh.RUnlock()
-		for _, host := range eh.hosts {
-			content += "\n"
-			content += host.Block()
+// String returns the complete hosts file content that Save would write
+func (eh *Hostfile) String() string {
+	var sb strings.Builder
+	_, _ = eh.WriteTo(&sb)
+	return sb.String()
+}
+
+// WriteTo writes the complete hosts file content to w, implementing the
+// io.WriterTo interface
+func (eh *Hostfile) WriteTo(w io.Writer) (n int64, err error) {
+	eh.RLock()
+	defer eh.RUnlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	count := eh.eachLine(func(_ *Host, number int, line string) {
+		if number > 1 {
+			cw.WriteString("\n")
 		}
-		err = cpaths.WriteFile(eh.Path, content)
-	} else {
-		err = fmt.Errorf("%v is not writable", eh.Path)
+		cw.WriteString(line)
+	})
+	if count > 0 && !eh.noFinalNewline {
+		cw.WriteString("\n")
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// eachLine calls fn with each line of the hosts file content, without the
+// newline but with the "\r" of CRLF line endings, along with its line number
+// and the host it belongs to, if any. The caller must hold at least the read
+// lock
+func (eh *Hostfile) eachLine(fn func(host *Host, number int, line string)) (count int) {
+	for _, line := range eh.header {
+		count += 1
+		fn(nil, count, line)
+	}
+	var previous *Host
+	for _, host := range eh.hosts {
+		hostLines := host.linesEndingWith(eh.lineEnd)
+		if len(hostLines) > 0 && count > 0 && !host.parsed() && !host.isGrouped() &&
+			!(previous != nil && previous.IsBlocked() && host.IsBlocked()) {
+			// new entries are separated from the previous entry, except for
+			// grouped and blocking entries which are listed together
+			count += 1
+			fn(nil, count, eh.lineEnd)
+		}
+		for _, line := range hostLines {
+			count += 1
+			fn(host, count, line)
+		}
+		previous = host
+	}
+	for _, line := range eh.trailing {
+		count += 1
+		fn(nil, count, line)
 	}
 	return
 }
 
-func (eh *Hostfile) Validate() (errs []error) {
-	for required, label := range HostValidations {
-		found := false
-		for _, host := range eh.hosts {
-			if host.HasDomain(required) {
-				found = true
-				break
-			}
+// LineNumbers returns the line number of each host within the content Save
+// would write, which is the host line itself for host entries and the first
+// comment line for comment entries
+func (eh *Hostfile) LineNumbers() (numbers map[*Host]int) {
+	spans := eh.lineSpans()
+	numbers = make(map[*Host]int, len(spans))
+	for host, span := range spans {
+		numbers[host] = span.number
+	}
+	return
+}
+
+// lineSpan is where a host is written, see LineNumbers. The count of lines
+// and whether the host is blocked are what decide the lines of the hosts
+// after it
+type lineSpan struct {
+	number  int
+	count   int
+	blocked bool
+}
+
+func (eh *Hostfile) lineSpans() (spans map[*Host]lineSpan) {
+	eh.RLock()
+	defer eh.RUnlock()
+	spans = make(map[*Host]lineSpan, len(eh.hosts))
+	eh.eachLine(func(host *Host, number int, line string) {
+		if host == nil {
+			return
 		}
-		if !found {
-			errs = append(errs, fmt.Errorf("missing required entry for %v", label))
+		span, found := spans[host]
+		if !found || !host.IsOnlyComment() {
+			span.number = number
 		}
+		span.count += 1
+		spans[host] = span
+	})
+	for host, span := range spans {
+		if host.IsOnlyComment() {
+			host.RLock()
+			span.number += len(host.leading)
+			host.RUnlock()
+		}
+		span.blocked = host.IsBlocked()
+		spans[host] = span
+	}
+	return
+}
+
+// Save writes the hosts file to Path, see saveAtomic for details. If the
+// file was modified by something else since it was parsed, Save returns an
+// error wrapping ErrModified and nothing is written; use Merge to combine
+// the changes or Overwrite to discard the other changes
+func (eh *Hostfile) Save() (err error) {
+	var modified bool
+	if modified, err = eh.Modified(); err != nil {
+		return
+	} else if modified {
+		return fmt.Errorf("%v: %w", eh.Path, ErrModified)
+	}
+	return eh.Overwrite()
+}
+
+// Overwrite is like Save without checking for external modifications
+func (eh *Hostfile) Overwrite() (err error) {
+	if !cpaths.FileWritable(eh.Path) {
+		return fmt.Errorf("%v is not writable", eh.Path)
+	}
+	var target string
+	if target, err = filepath.EvalSymlinks(eh.Path); err != nil {
+		return
+	}
+	content := eh.String()
+	if err = eh.saveAtomic(target); err != nil {
+		return
+	}
+	var info os.FileInfo
+	if info, err = os.Stat(target); err != nil {
+		return
 	}
+	eh.Lock()
+	eh.loaded = eh.Path
+	eh.modTime = info.ModTime()
+	eh.checksum = sha256.Sum256([]byte(content))
+	eh.Unlock()
+	return
+}
+
+// ModTime returns the modification time of the file when it was parsed or
+// last saved
+func (eh *Hostfile) ModTime() time.Time {
+	eh.RLock()
+	defer eh.RUnlock()
+	return eh.modTime
+}
+
+// Changed reports whether the content Save would write is different from the
+// content when it was parsed or last saved, including entries added, removed
+// or moved
+func (eh *Hostfile) Changed() bool {
+	content := eh.String()
+	eh.RLock()
+	defer eh.RUnlock()
+	return sha256.Sum256([]byte(content)) != eh.checksum
+}
+
+// Modified reports whether the file at Path has different content than when
+// it was parsed or last saved
+func (eh *Hostfile) Modified() (modified bool, err error) {
+	eh.RLock()
+	path, loaded, checksum := eh.Path, eh.loaded, eh.checksum
+	eh.RUnlock()
+	if loaded == "" || loaded != path {
+		return
+	}
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+	modified = sha256.Sum256(data) != checksum
 	return
 }
 
@@ -84,22 +275,42 @@ func (eh *Hostfile) Len() int {
 	return len(eh.hosts)
 }
 
+// IndexOf returns the position of the host, or -1 if there is none. Only
+// the host itself is found, not others with the same content
 func (eh *Hostfile) IndexOf(host *Host) int {
 	eh.RLock()
 	defer eh.RUnlock()
-	if host != nil {
-		for i, h := range eh.hosts {
-			if h.Equals(host) {
-				return i
-			}
-		}
+	return eh.indexOf(host)
+}
+
+func (eh *Hostfile) indexOf(host *Host) int {
+	if host == nil {
+		return -1
+	}
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	if idx, found := eh.getIndex().positions[host]; found {
+		return idx
 	}
 	return -1
 }
 
+// HostByID returns the host with the ID given, or nil if there is none
+func (eh *Hostfile) HostByID(id HostID) (host *Host) {
+	eh.RLock()
+	defer eh.RUnlock()
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	return eh.getIndex().ids[id]
+}
+
+// InsertHost inserts the host at the position given, or at the end when out
+// of range. Unrecognized lines moved off the host when it was removed are
+// moved back onto it
 func (eh *Hostfile) InsertHost(host *Host, idx int) {
 	eh.Lock()
-	eh.hosts = eh.insertHost(eh.hosts, host, idx)
+	eh.reclaimTrivia(host)
+	eh.setHosts(eh.insertHost(eh.hosts, host, idx))
 	eh.Unlock()
 }
 
@@ -111,10 +322,190 @@ func (eh *Hostfile) insertHost(hosts []*Host, host *Host, idx int) []*Host {
 	return append(temp, host)
 }
 
-func (eh *Hostfile) RemoveHost(idx int) {
+// RemoveHost removes the host, returning the position it was removed from
+// or -1 if it was not present
+func (eh *Hostfile) RemoveHost(host *Host) (idx int) {
 	eh.Lock()
-	eh.hosts = eh.removeHost(eh.hosts, idx)
-	eh.Unlock()
+	defer eh.Unlock()
+	if idx = eh.indexOf(host); idx >= 0 {
+		eh.keepTrivia(idx)
+		eh.setHosts(eh.removeHost(eh.hosts, idx))
+	}
+	return
+}
+
+// trivia is the unrecognized lines moved off a removed host onto the next
+// host, or the end of the file when next is nil, see keepTrivia
+type trivia struct {
+	next    *Host
+	lines   []string
+	leading []string
+}
+
+// keepTrivia moves the unrecognized lines preceding the host at idx onto the
+// next host (or the end of the file) so that removing an entry does not also
+// remove content eheditor does not understand
+func (eh *Hostfile) keepTrivia(idx int) {
+	if idx < 0 || idx >= len(eh.hosts) {
+		return
+	}
+	var next *Host
+	if idx+1 < len(eh.hosts) {
+		next = eh.hosts[idx+1]
+	}
+	if moved := stripTrivia(eh.hosts[idx]); moved != nil {
+		moved.next = next
+		eh.prependTrivia(next, moved.lines)
+	}
+}
+
+// stripTrivia removes the unrecognized lines from the leading lines of the
+// host, returning them (without a next host yet) or nil if there are none
+func stripTrivia(host *Host) (moved *trivia) {
+	host.Lock()
+	defer host.Unlock()
+	var kept, blank []string
+	for _, line := range host.leading {
+		if rxEmptyLine.MatchString(line) {
+			blank = append(blank, line)
+		} else {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == 0 {
+		host.moved = nil
+		return
+	}
+	moved = &trivia{lines: kept, leading: host.leading}
+	host.leading, host.moved = blank, moved
+	return
+}
+
+// prependTrivia adds the lines before the next host, or the end of the file
+func (eh *Hostfile) prependTrivia(next *Host, lines []string) {
+	if next == nil {
+		eh.trailing = append(append([]string{}, lines...), eh.trailing...)
+		return
+	}
+	next.Lock()
+	next.leading = append(append([]string{}, lines...), next.leading...)
+	next.Unlock()
+}
+
+// reclaimTrivia moves the lines keepTrivia moved off the host back onto it,
+// provided they are still where they were moved to
+func (eh *Hostfile) reclaimTrivia(host *Host) {
+	host.Lock()
+	moved := host.moved
+	host.moved = nil
+	host.Unlock()
+	if moved == nil {
+		return
+	}
+
+	var found bool
+	if moved.next == nil {
+		eh.trailing, found = cutLines(eh.trailing, moved.lines)
+	} else {
+		moved.next.Lock()
+		moved.next.leading, found = cutLines(moved.next.leading, moved.lines)
+		moved.next.Unlock()
+	}
+	if found {
+		host.Lock()
+		host.leading = moved.leading
+		host.Unlock()
+	}
+}
+
+// cutLines removes the first run of the lines given from all the lines
+func cutLines(all, lines []string) (remaining []string, found bool) {
+	for start := 0; start+len(lines) <= len(all); start++ {
+		if cstrings.EqualStringSlices(all[start:start+len(lines)], lines) {
+			remaining = append(append([]string{}, all[:start]...), all[start+len(lines):]...)
+			return remaining, true
+		}
+	}
+	return all, false
+}
+
+// RemoveHosts removes all the hosts at once, returning the position each
+// was removed from or -1 for the ones not present. As with RemoveHost, the
+// unrecognized lines preceding each host are kept, and moved back by
+// InsertHosts
+func (eh *Hostfile) RemoveHosts(hosts ...*Host) (positions []int) {
+	eh.Lock()
+	defer eh.Unlock()
+	positions = make([]int, len(hosts))
+	removing := make(map[*Host]bool, len(hosts))
+	for idx, host := range hosts {
+		if positions[idx] = eh.indexOf(host); positions[idx] >= 0 {
+			removing[host] = true
+		}
+	}
+	if len(removing) == 0 {
+		return
+	}
+	remaining := make([]*Host, 0, len(eh.hosts)-len(removing))
+	var pending []*trivia
+	var kept []string
+	keep := func(next *Host) {
+		for _, moved := range pending {
+			moved.next = next
+		}
+		eh.prependTrivia(next, kept)
+		pending, kept = nil, nil
+	}
+	for _, host := range eh.hosts {
+		if removing[host] {
+			if moved := stripTrivia(host); moved != nil {
+				pending = append(pending, moved)
+				kept = append(kept, moved.lines...)
+			}
+			continue
+		}
+		if len(pending) > 0 {
+			keep(host)
+		}
+		remaining = append(remaining, host)
+	}
+	if len(pending) > 0 {
+		keep(nil)
+	}
+	eh.setHosts(remaining)
+	return
+}
+
+// InsertHosts inserts all the hosts at once, each at the position given
+// for it (as returned by RemoveHosts), positions less than zero are skipped.
+// As with InsertHost, the unrecognized lines moved off the hosts are moved
+// back
+func (eh *Hostfile) InsertHosts(hosts []*Host, positions []int) {
+	var order []int
+	for idx := range hosts {
+		if idx < len(positions) && positions[idx] >= 0 {
+			order = append(order, idx)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return positions[order[i]] < positions[order[j]]
+	})
+
+	eh.Lock()
+	defer eh.Unlock()
+	for _, idx := range order {
+		eh.reclaimTrivia(hosts[idx])
+	}
+	updated := make([]*Host, 0, len(eh.hosts)+len(order))
+	next := 0
+	for _, idx := range order {
+		for len(updated) < positions[idx] && next < len(eh.hosts) {
+			updated = append(updated, eh.hosts[next])
+			next += 1
+		}
+		updated = append(updated, hosts[idx])
+	}
+	eh.setHosts(append(updated, eh.hosts[next:]...))
 }
 
 func (eh *Hostfile) removeHost(hosts []*Host, idx int) []*Host {
@@ -125,10 +516,15 @@ func (eh *Hostfile) removeHost(hosts []*Host, idx int) []*Host {
 	return temp
 }
 
-func (eh *Hostfile) MoveHost(from, to int) {
+// MoveHost moves the host to the position given, returning the position it
+// was moved from or -1 if it was not present
+func (eh *Hostfile) MoveHost(host *Host, to int) (from int) {
 	eh.Lock()
-	eh.hosts = eh.moveHost(eh.hosts, from, to)
-	eh.Unlock()
+	defer eh.Unlock()
+	if from = eh.indexOf(host); from >= 0 {
+		eh.setHosts(eh.moveHost(eh.hosts, from, to))
+	}
+	return
 }
 
 func (eh *Hostfile) moveHost(hosts []*Host, from, to int) (updated []*Host) {
@@ -0,0 +1,248 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, content string) *Hostfile {
+	t.Helper()
+	eh, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", content, err)
+	}
+	return eh
+}
+
+func hostWithDomain(t *testing.T, eh *Hostfile, domain string) *Host {
+	t.Helper()
+	hosts := eh.HostsForDomain(domain)
+	if len(hosts) == 0 {
+		t.Fatalf("no host with %v", domain)
+	}
+	return hosts[0]
+}
+
+func TestRemoveHostKeepsTrivia(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		domain  string
+		removed string
+	}{
+		{
+			name:    "before next host",
+			content: "1.1.1.1 a\nweird line\n10.0.0.7 x\n2.2.2.2 y\n",
+			domain:  "x",
+			removed: "1.1.1.1 a\nweird line\n2.2.2.2 y\n",
+		},
+		{
+			name:    "at the end",
+			content: "1.1.1.1 a\nweird line\n10.0.0.7 x\n",
+			domain:  "x",
+			removed: "1.1.1.1 a\nweird line\n",
+		},
+		{
+			name:    "with blank lines",
+			content: "1.1.1.1 a\n\nweird line\n\n10.0.0.7 x\n2.2.2.2 y\n",
+			domain:  "x",
+			removed: "1.1.1.1 a\nweird line\n2.2.2.2 y\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			host := hostWithDomain(t, eh, test.domain)
+			idx := eh.RemoveHost(host)
+			if got := eh.String(); got != test.removed {
+				t.Errorf("after RemoveHost:\n%q\nwant\n%q", got, test.removed)
+			}
+			eh.InsertHost(host, idx)
+			if got := eh.String(); got != test.content {
+				t.Errorf("after InsertHost:\n%q\nwant\n%q", got, test.content)
+			}
+			eh.RemoveHost(host)
+			if got := eh.String(); got != test.removed {
+				t.Errorf("after removing again:\n%q\nwant\n%q", got, test.removed)
+			}
+		})
+	}
+}
+
+func TestRemoveHostsKeepsTrivia(t *testing.T) {
+	content := "1.1.1.1 a\nweird one\n10.0.0.7 x\nweird two\n10.0.0.8 z\n2.2.2.2 y\n"
+	eh := mustParse(t, content)
+	hosts := []*Host{hostWithDomain(t, eh, "x"), hostWithDomain(t, eh, "z")}
+
+	positions := eh.RemoveHosts(hosts...)
+	if got, want := eh.String(), "1.1.1.1 a\nweird one\nweird two\n2.2.2.2 y\n"; got != want {
+		t.Errorf("after RemoveHosts:\n%q\nwant\n%q", got, want)
+	}
+	eh.InsertHosts(hosts, positions)
+	if got := eh.String(); got != content {
+		t.Errorf("after InsertHosts:\n%q\nwant\n%q", got, content)
+	}
+}
+
+func TestMoveHostsToBottom(t *testing.T) {
+	content := "1.1.1.1 a\nweird line\n10.0.0.7 x\n2.2.2.2 y\n"
+	eh := mustParse(t, content)
+	host := hostWithDomain(t, eh, "x")
+
+	eh.RemoveHosts(host)
+	eh.InsertHosts([]*Host{host}, []int{eh.Len()})
+	got := eh.String()
+	if count := strings.Count(got, "weird line"); count != 1 {
+		t.Errorf("bulk move wrote the kept line %d times:\n%q", count, got)
+	}
+
+	single := mustParse(t, content)
+	single.MoveHost(hostWithDomain(t, single, "x"), single.Len()-1)
+	if want := single.String(); got != want {
+		t.Errorf("bulk move:\n%q\nsingle move:\n%q", got, want)
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	eh := mustParse(t, "1.1.1.1 a b\n2.2.2.2 B c\n3.3.3.3 c a\n")
+	a, b, c := eh.Hosts()[0], eh.Hosts()[1], eh.Hosts()[2]
+	eh.HostsForDomain("a") // build the index, which the edits then update
+	for _, edit := range []struct {
+		name string
+		fn   func()
+	}{
+		{"set domains", func() { a.SetDomains("c d") }},
+		{"set address", func() { b.SetAddress("3.3.3.3") }},
+		{"add domain", func() { c.AddDomain("A") }},
+		{"remove domain", func() { c.RemoveDomain("c") }},
+		{"set entry", func() { a.SetEntry(Entry{Active: true, Address: "4.4.4.4", Domains: []string{"b", "b"}}) }},
+	} {
+		edit.fn()
+		updated := eh.getIndex()
+		fresh := newHostIndex(eh.Hosts())
+		for key, hosts := range fresh.domains {
+			if got := eh.HostsForDomain(key); !equalHosts(got, hosts) {
+				t.Errorf("%v: HostsForDomain(%q) = %v, want %v", edit.name, key, got, hosts)
+			}
+		}
+		for key, hosts := range fresh.addresses {
+			if got := eh.HostsForAddress(key); !equalHosts(got, hosts) {
+				t.Errorf("%v: HostsForAddress(%q) = %v, want %v", edit.name, key, got, hosts)
+			}
+		}
+		if len(updated.domains) != len(fresh.domains) || len(updated.addresses) != len(fresh.addresses) {
+			t.Errorf("%v: index has %d domains and %d addresses, want %d and %d", edit.name,
+				len(updated.domains), len(updated.addresses), len(fresh.domains), len(fresh.addresses))
+		}
+		if got := eh.Domains(); strings.Join(got, " ") != strings.Join(fresh.domainOrder, " ") {
+			t.Errorf("%v: Domains() = %v, want %v", edit.name, got, fresh.domainOrder)
+		}
+		if got := eh.Addresses(); strings.Join(got, " ") != strings.Join(fresh.addressOrder, " ") {
+			t.Errorf("%v: Addresses() = %v, want %v", edit.name, got, fresh.addressOrder)
+		}
+	}
+}
+
+func TestHostByID(t *testing.T) {
+	eh := mustParse(t, "# header\n\n###\n# a comment entry\n###\n\n1.1.1.1 a\n2.2.2.2 b\n")
+	hosts := eh.Hosts()
+	for _, host := range hosts {
+		if got := eh.HostByID(host.ID()); got != host {
+			t.Errorf("HostByID(%v) = %v, want %v", host.ID(), got, host)
+		}
+	}
+	if got := eh.HostByID(NewHost("3.3.3.3", "c").ID()); got != nil {
+		t.Errorf("HostByID() of a host not in the file = %v", got)
+	}
+
+	removed := hosts[1]
+	idx := eh.RemoveHost(removed)
+	if got := eh.HostByID(removed.ID()); got != nil {
+		t.Errorf("HostByID() of a removed host = %v", got)
+	}
+	eh.InsertHost(removed, idx)
+	if got := eh.HostByID(removed.ID()); got != removed {
+		t.Errorf("HostByID() of a host inserted again = %v, want %v", got, removed)
+	}
+}
+
+func equalHosts(a, b []*Host) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHostfileChanged(t *testing.T) {
+	const content = "127.0.0.1\tlocalhost\n10.0.0.1 alpha\n10.0.0.2 beta\n"
+	for _, test := range []struct {
+		name string
+		edit func(eh *Hostfile)
+		want bool
+	}{
+		{"unchanged", func(eh *Hostfile) {}, false},
+		{"edited", func(eh *Hostfile) { hostWithDomain(t, eh, "alpha").SetDomains("gamma") }, true},
+		{"edit undone", func(eh *Hostfile) {
+			host := hostWithDomain(t, eh, "alpha")
+			host.SetDomains("gamma")
+			host.SetDomains("alpha")
+		}, false},
+		{"removed", func(eh *Hostfile) { eh.RemoveHost(hostWithDomain(t, eh, "alpha")) }, true},
+		{"removed hosts", func(eh *Hostfile) { eh.RemoveHosts(hostWithDomain(t, eh, "beta")) }, true},
+		{"inserted", func(eh *Hostfile) { eh.InsertHost(NewHost("10.0.0.3", "gamma"), -1) }, true},
+		{"moved", func(eh *Hostfile) { eh.MoveHost(hostWithDomain(t, eh, "beta"), 0) }, true},
+		{"removal undone", func(eh *Hostfile) {
+			host := hostWithDomain(t, eh, "alpha")
+			eh.InsertHost(host, eh.RemoveHost(host))
+		}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, content)
+			test.edit(eh)
+			if got := eh.Changed(); got != test.want {
+				t.Errorf("Changed() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHostfileChangedAfterSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("127.0.0.1\tlocalhost\n10.0.0.1 alpha\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eh, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eh.RemoveHost(hostWithDomain(t, eh, "alpha"))
+	if !eh.Changed() {
+		t.Fatal("Changed() = false after removing a host")
+	}
+	if err = eh.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if eh.Changed() {
+		t.Error("Changed() = true after saving")
+	}
+}
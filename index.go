@@ -0,0 +1,293 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"sort"
+	"strings"
+)
+
+// hostIndex holds the positions and IDs of the hosts and the hosts for each
+// domain and address. It is built when first needed, cleared whenever the hosts
+// change and updated when the address or domains of a host change
+type hostIndex struct {
+	positions map[*Host]int
+	ids       map[HostID]*Host
+	domains   map[string][]*Host
+	addresses map[string][]*Host
+	// domainOrder and addressOrder are the keys in order of appearance, which
+	// are found again by orderKeys when an update leaves them out of order
+	domainOrder  []string
+	addressOrder []string
+	ordered      bool
+}
+
+func newHostIndex(hosts []*Host) (idx *hostIndex) {
+	idx = &hostIndex{
+		positions: make(map[*Host]int, len(hosts)),
+		ids:       make(map[HostID]*Host, len(hosts)),
+		domains:   make(map[string][]*Host),
+		addresses: make(map[string][]*Host),
+		ordered:   true,
+	}
+	for pos, host := range hosts {
+		if _, found := idx.positions[host]; found {
+			continue
+		}
+		idx.positions[host] = pos
+		idx.ids[host.id] = host
+		if host.IsOnlyComment() {
+			continue
+		}
+		if address := host.Address(); address != "" {
+			if _, found := idx.addresses[address]; !found {
+				idx.addressOrder = append(idx.addressOrder, address)
+			}
+			idx.addresses[address] = append(idx.addresses[address], host)
+		}
+		for _, domain := range host.Domains() {
+			if domain == "" {
+				continue
+			}
+			key := strings.ToLower(domain)
+			if _, found := idx.domains[key]; !found {
+				idx.domainOrder = append(idx.domainOrder, domain)
+			} else if last := idx.domains[key][len(idx.domains[key])-1]; last == host {
+				continue // the same domain listed twice by one host
+			}
+			idx.domains[key] = append(idx.domains[key], host)
+		}
+	}
+	return
+}
+
+// orderKeys finds the domains and addresses in order of appearance again
+func (idx *hostIndex) orderKeys(hosts []*Host) {
+	idx.domainOrder, idx.addressOrder = nil, nil
+	seen := make(map[string]bool)
+	for pos, host := range hosts {
+		if idx.positions[host] != pos || host.IsOnlyComment() {
+			continue
+		}
+		if address := host.Address(); address != "" && idx.addresses[address][0] == host {
+			idx.addressOrder = append(idx.addressOrder, address)
+		}
+		for _, domain := range host.Domains() {
+			key := strings.ToLower(domain)
+			if domain != "" && !seen[key] && idx.domains[key][0] == host {
+				seen[key] = true
+				idx.domainOrder = append(idx.domainOrder, domain)
+			}
+		}
+	}
+	idx.ordered = true
+}
+
+// update moves the host from the hosts for the address and domains given,
+// which it had before, to the hosts for its current address and domains. The
+// other hosts for either are returned
+func (idx *hostIndex) update(host *Host, address string, domains []string) (related []*Host) {
+	pos, found := idx.positions[host]
+	if !found || host.IsOnlyComment() {
+		return
+	}
+	if address != "" {
+		related = append(related, idx.unlist(idx.addresses, address, host)...)
+	}
+	for _, domain := range domains {
+		if domain != "" {
+			related = append(related, idx.unlist(idx.domains, strings.ToLower(domain), host)...)
+		}
+	}
+	if address = host.Address(); address != "" {
+		related = append(related, idx.enlist(idx.addresses, address, host, pos)...)
+	}
+	for _, domain := range host.Domains() {
+		if domain != "" {
+			related = append(related, idx.enlist(idx.domains, strings.ToLower(domain), host, pos)...)
+		}
+	}
+	return
+}
+
+// unlist removes the host from the hosts for the key and returns the others
+func (idx *hostIndex) unlist(lists map[string][]*Host, key string, host *Host) (others []*Host) {
+	list := lists[key]
+	at := 0
+	for at < len(list) && list[at] != host {
+		at += 1
+	}
+	if at == len(list) {
+		return list
+	}
+	list = append(list[:at], list[at+1:]...)
+	idx.ordered = idx.ordered && at > 0
+	if len(list) == 0 {
+		delete(lists, key)
+		idx.ordered = false
+		return
+	}
+	lists[key] = list
+	return list
+}
+
+// enlist adds the host at pos to the hosts for the key, keeping them in the
+// order of the hosts file, and returns the others
+func (idx *hostIndex) enlist(lists map[string][]*Host, key string, host *Host, pos int) (others []*Host) {
+	list := lists[key]
+	at := sort.Search(len(list), func(i int) bool {
+		return idx.positions[list[i]] >= pos
+	})
+	if at < len(list) && list[at] == host {
+		// the same key listed twice by the host
+		return
+	}
+	others = append(others, list...)
+	list = append(list, nil)
+	copy(list[at+1:], list[at:])
+	list[at] = host
+	lists[key] = list
+	idx.ordered = idx.ordered && at > 0
+	return
+}
+
+// getIndex returns the index of the hosts, building it if needed. The caller
+// must hold indexLock and at least the read lock
+func (eh *Hostfile) getIndex() (idx *hostIndex) {
+	if eh.index == nil {
+		eh.index = newHostIndex(eh.hosts)
+	}
+	return eh.index
+}
+
+// clearIndex discards the index of the hosts, it is built again when next
+// needed
+func (eh *Hostfile) clearIndex() {
+	eh.indexLock.Lock()
+	eh.index = nil
+	eh.validated = false
+	eh.indexLock.Unlock()
+}
+
+// updateIndex updates the index after the address or domains of the host
+// changed from those given, or after other changes that may change the
+// findings of the host when they are the same
+func (eh *Hostfile) updateIndex(host *Host, address string, domains []string) {
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	if eh.index == nil {
+		// the hosts related to this one are not known
+		eh.validated = false
+		return
+	}
+	related := eh.index.update(host, address, domains)
+	if eh.validated {
+		eh.changed[host] = true
+		for _, other := range related {
+			eh.changed[other] = true
+		}
+	}
+}
+
+// setHosts replaces the hosts, making eh their owner, and clears the index.
+// The caller must hold the write lock
+func (eh *Hostfile) setHosts(hosts []*Host) {
+	for _, host := range hosts {
+		host.Lock()
+		host.owner = eh
+		host.Unlock()
+	}
+	eh.hosts = hosts
+	eh.clearIndex()
+}
+
+// HostsForDomain returns the hosts with the domain, ignoring case, in the
+// order of the hosts file
+func (eh *Hostfile) HostsForDomain(domain string) (hosts []*Host) {
+	eh.RLock()
+	defer eh.RUnlock()
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	return append(hosts, eh.getIndex().domains[strings.ToLower(domain)]...)
+}
+
+// HostsForAddress returns the hosts with the address, in the order of the
+// hosts file
+func (eh *Hostfile) HostsForAddress(address string) (hosts []*Host) {
+	eh.RLock()
+	defer eh.RUnlock()
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	return append(hosts, eh.getIndex().addresses[address]...)
+}
+
+// Domains returns each of the domains of the hosts once, in the order they
+// first appear and as first written when they appear with different case
+func (eh *Hostfile) Domains() (domains []string) {
+	eh.RLock()
+	defer eh.RUnlock()
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	if idx := eh.getIndex(); !idx.ordered {
+		idx.orderKeys(eh.hosts)
+	}
+	return append(domains, eh.index.domainOrder...)
+}
+
+// Addresses returns each of the addresses of the hosts once, in the order
+// they first appear
+func (eh *Hostfile) Addresses() (addresses []string) {
+	eh.RLock()
+	defer eh.RUnlock()
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	if idx := eh.getIndex(); !idx.ordered {
+		idx.orderKeys(eh.hosts)
+	}
+	return append(addresses, eh.index.addressOrder...)
+}
+
+// sortHosts orders the hosts as in the hosts file
+func (eh *Hostfile) sortHosts(hosts []*Host) {
+	eh.RLock()
+	defer eh.RUnlock()
+	eh.indexLock.Lock()
+	defer eh.indexLock.Unlock()
+	positions := eh.getIndex().positions
+	sort.Slice(hosts, func(i, j int) bool {
+		return positions[hosts[i]] < positions[hosts[j]]
+	})
+}
+
+// Each calls fn with each host and its position, in order, until fn returns
+// false. The hosts are those present when Each was called
+func (eh *Hostfile) Each(fn func(idx int, host *Host) (more bool)) {
+	for idx, host := range eh.Hosts() {
+		if !fn(idx, host) {
+			return
+		}
+	}
+}
+
+// Filter returns the hosts for which fn returns true, in order
+func (eh *Hostfile) Filter(fn func(host *Host) (keep bool)) (hosts []*Host) {
+	eh.Each(func(_ int, host *Host) bool {
+		if fn(host) {
+			hosts = append(hosts, host)
+		}
+		return true
+	})
+	return
+}
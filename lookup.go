@@ -0,0 +1,167 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+
+	"github.com/go-curses/cdk/lib/sync"
+)
+
+// LookupPolicy is how Refresh chooses an address when the current address of
+// an entry is not among those found for its lookup domain. The policy is
+// given after the domain of the #nslookup line, for example:
+//
+//	#nslookup example.com ipv6
+type LookupPolicy string
+
+const (
+	// LookupFirst chooses the first address found
+	LookupFirst LookupPolicy = "first"
+	// LookupLowest chooses the lowest address found, which is stable when
+	// the order of the answers varies
+	LookupLowest LookupPolicy = "lowest"
+	// LookupIPv4 chooses the first IPv4 address found
+	LookupIPv4 LookupPolicy = "ipv4"
+	// LookupIPv6 chooses the first IPv6 address found
+	LookupIPv6 LookupPolicy = "ipv6"
+)
+
+// DefaultLookupPolicy is used for entries without a LookupPolicy
+var DefaultLookupPolicy = LookupFirst
+
+// Valid reports whether the policy is empty or one of the known policies
+func (p LookupPolicy) Valid() bool {
+	switch p {
+	case "", LookupFirst, LookupLowest, LookupIPv4, LookupIPv6:
+		return true
+	}
+	return false
+}
+
+// Choose returns the address to use given the current one and those found,
+// which is the current address when it is still acceptable. Choose returns
+// false when none of the addresses found are acceptable
+func (p LookupPolicy) Choose(current string, found []net.IP) (address string, ok bool) {
+	var candidates []net.IP
+	for _, ip := range found {
+		switch p {
+		case LookupIPv4:
+			if ip.To4() == nil {
+				continue
+			}
+		case LookupIPv6:
+			if ip.To4() != nil {
+				continue
+			}
+		}
+		candidates = append(candidates, ip)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	if ip := net.ParseIP(current); ip != nil {
+		for _, candidate := range candidates {
+			if candidate.Equal(ip) {
+				return current, true
+			}
+		}
+	}
+	if p == LookupLowest {
+		sort.Slice(candidates, func(i, j int) bool {
+			return bytes.Compare(candidates[i].To16(), candidates[j].To16()) < 0
+		})
+	}
+	return candidates[0].String(), true
+}
+
+// RefreshResult is the outcome of refreshing one entry with a lookup domain
+type RefreshResult struct {
+	Host     *Host
+	Lookup   string
+	Policy   LookupPolicy
+	Previous string
+	Address  string
+	Found    []net.IP
+	Err      error
+}
+
+// Changed reports whether the address of the entry was updated
+func (r RefreshResult) Changed() bool {
+	return r.Err == nil && r.Address != r.Previous
+}
+
+// Refresh resolves the lookup domain of every entry which has one with the
+// resolver, or the DefaultResolver when nil, using up to the given number of
+// concurrent lookups. Each address which is no longer among those found is
+// updated according to the entry's LookupPolicy, or the policy given when the
+// entry has none, or the DefaultLookupPolicy when that is empty too. The
+// results are in entry order
+func (eh *Hostfile) Refresh(ctx context.Context, resolver Resolver, policy LookupPolicy, concurrency int) (results []RefreshResult) {
+	if resolver == nil {
+		resolver = DefaultResolver
+	}
+	if policy == "" || !policy.Valid() {
+		policy = DefaultLookupPolicy
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for _, host := range eh.Hosts() {
+		if lookup := host.Lookup(); lookup != "" && !host.IsOnlyComment() {
+			entryPolicy := host.LookupPolicy()
+			if entryPolicy == "" || !entryPolicy.Valid() {
+				entryPolicy = policy
+			}
+			results = append(results, RefreshResult{
+				Host:     host,
+				Lookup:   lookup,
+				Policy:   entryPolicy,
+				Previous: host.Address(),
+			})
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for idx := range results {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(result *RefreshResult) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if result.Found, result.Err = result.Host.LookupWith(ctx, resolver); result.Err != nil {
+				return
+			}
+			var ok bool
+			if result.Address, ok = result.Policy.Choose(result.Previous, result.Found); !ok {
+				result.Err = &net.DNSError{Err: "no " + string(result.Policy) + " address found", Name: result.Lookup}
+			}
+		}(&results[idx])
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Changed() {
+			result.Host.SetAddress(result.Address)
+		}
+	}
+	return
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-curses/cdk/lib/paint"
+	"github.com/go-curses/cdk/lib/sync"
+)
+
+// stubResolver answers lookups from a map, counting them, and fails for any
+// other name
+type stubResolver struct {
+	answers map[string][]string
+	lookups map[string]int
+
+	sync.Mutex
+}
+
+func newStubResolver(answers map[string][]string) *stubResolver {
+	return &stubResolver{answers: answers, lookups: make(map[string]int)}
+}
+
+func (r *stubResolver) LookupIP(ctx context.Context, name string) (found []net.IP, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.lookups[name] += 1
+	addresses, ok := r.answers[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	for _, address := range addresses {
+		found = append(found, net.ParseIP(address))
+	}
+	return
+}
+
+// resolverFunc is a Resolver which calls the function
+type resolverFunc func(ctx context.Context, name string) ([]net.IP, error)
+
+func (f resolverFunc) LookupIP(ctx context.Context, name string) ([]net.IP, error) {
+	return f(ctx, name)
+}
+
+func parseIPs(addresses ...string) (ips []net.IP) {
+	for _, address := range addresses {
+		ips = append(ips, net.ParseIP(address))
+	}
+	return
+}
+
+func TestLookupPolicyChoose(t *testing.T) {
+	found := parseIPs("10.0.0.9", "2001:db8::2", "10.0.0.3", "2001:db8::1")
+	for _, test := range []struct {
+		policy  LookupPolicy
+		current string
+		found   []net.IP
+		want    string
+		ok      bool
+	}{
+		{LookupFirst, "192.168.0.1", found, "10.0.0.9", true},
+		{LookupFirst, "10.0.0.3", found, "10.0.0.3", true},
+		{LookupFirst, "2001:0db8::0001", found, "2001:0db8::0001", true},
+		{LookupLowest, "192.168.0.1", found, "10.0.0.3", true},
+		{LookupLowest, "10.0.0.9", found, "10.0.0.9", true},
+		{LookupIPv4, "2001:db8::1", found, "10.0.0.9", true},
+		{LookupIPv4, "10.0.0.3", found, "10.0.0.3", true},
+		{LookupIPv6, "10.0.0.3", found, "2001:db8::2", true},
+		{LookupIPv6, "2001:db8::1", found, "2001:db8::1", true},
+		{LookupIPv6, "10.0.0.3", parseIPs("10.0.0.3"), "", false},
+		{LookupFirst, "10.0.0.3", nil, "", false},
+	} {
+		if got, ok := test.policy.Choose(test.current, test.found); got != test.want || ok != test.ok {
+			t.Errorf("%v.Choose(%v, %v) = %v, %v, want %v, %v", test.policy, test.current, test.found, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	const content = `127.0.0.1 localhost
+#nslookup moved.example
+10.0.0.1 moved.example
+#nslookup same.example
+10.0.0.2 same.example
+#nslookup dual.example ipv6
+10.0.0.3 dual.example
+#nslookup dual.example
+10.0.0.4 dual.other
+#nslookup lowest.example lowest
+10.0.0.5 lowest.example
+#nslookup missing.example
+10.0.0.6 missing.example
+#nslookup v4only.example ipv6
+10.0.0.7 v4only.example
+`
+	resolver := newStubResolver(map[string][]string{
+		"moved.example":  {"10.1.0.1"},
+		"same.example":   {"10.1.0.2", "10.0.0.2"},
+		"dual.example":   {"10.1.0.3", "2001:db8::3"},
+		"lowest.example": {"10.9.0.5", "10.1.0.5", "10.5.0.5"},
+		"v4only.example": {"10.1.0.7"},
+	})
+	eh := mustParse(t, content)
+	results := eh.Refresh(context.Background(), resolver, LookupIPv4, 3)
+
+	type outcome struct {
+		lookup, policy, previous, address string
+		changed, failed                   bool
+	}
+	var got []outcome
+	for _, result := range results {
+		got = append(got, outcome{result.Lookup, string(result.Policy), result.Previous, result.Address, result.Changed(), result.Err != nil})
+	}
+	want := []outcome{
+		{"moved.example", "ipv4", "10.0.0.1", "10.1.0.1", true, false},
+		{"same.example", "ipv4", "10.0.0.2", "10.0.0.2", false, false},
+		{"dual.example", "ipv6", "10.0.0.3", "2001:db8::3", true, false},
+		{"dual.example", "ipv4", "10.0.0.4", "10.1.0.3", true, false},
+		{"lowest.example", "lowest", "10.0.0.5", "10.1.0.5", true, false},
+		{"missing.example", "ipv4", "10.0.0.6", "", false, true},
+		{"v4only.example", "ipv6", "10.0.0.7", "", false, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Refresh() =\n%+v\nwant\n%+v", got, want)
+	}
+	if err := results[6].Err; err == nil || !strings.Contains(err.Error(), "no ipv6 address found") {
+		t.Errorf("Refresh() error = %v, want no ipv6 address found", err)
+	}
+	if resolver.lookups["dual.example"] != 2 || resolver.lookups["moved.example"] != 1 {
+		t.Errorf("Refresh() lookups = %v", resolver.lookups)
+	}
+
+	wantContent := strings.NewReplacer(
+		"10.0.0.1 ", "10.1.0.1 ",
+		"10.0.0.3 ", "2001:db8::3 ",
+		"10.0.0.4 ", "10.1.0.3 ",
+		"10.0.0.5 ", "10.1.0.5 ",
+	).Replace(content)
+	if got := eh.String(); got != wantContent {
+		t.Errorf("Refresh() changed the hosts to\n%v\nwant\n%v", got, wantContent)
+	}
+	if host := hostWithDomain(t, eh, "dual.other"); host.Address() != "10.1.0.3" {
+		t.Errorf("dual.other address = %v, want 10.1.0.3", host.Address())
+	}
+}
+
+func TestRefreshDefaultPolicy(t *testing.T) {
+	resolver := newStubResolver(map[string][]string{"moved.example": {"2001:db8::1", "10.1.0.1"}})
+	for _, policy := range []LookupPolicy{"", "nearest"} {
+		eh := mustParse(t, "#nslookup moved.example\n10.0.0.1 moved.example\n")
+		results := eh.Refresh(context.Background(), resolver, policy, 1)
+		if len(results) != 1 || results[0].Err != nil || results[0].Policy != DefaultLookupPolicy || results[0].Address != "2001:db8::1" {
+			t.Errorf("Refresh() with the policy %q = %+v, want the %v address", policy, results, DefaultLookupPolicy)
+		}
+	}
+}
+
+func TestRefreshCancelled(t *testing.T) {
+	eh := mustParse(t, "#nslookup slow.example\n10.0.0.1 slow.example\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resolver := resolverFunc(func(ctx context.Context, name string) ([]net.IP, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	results := eh.Refresh(ctx, resolver, LookupFirst, 1)
+	if len(results) != 1 || results[0].Err != context.Canceled || results[0].Changed() {
+		t.Errorf("Refresh() = %+v, want a cancelled result", results)
+	} else if got := hostWithDomain(t, eh, "slow.example").Address(); got != "10.0.0.1" {
+		t.Errorf("address = %v after a cancelled refresh", got)
+	}
+}
+
+func TestGetActualInfoWith(t *testing.T) {
+	checked := string(paint.RuneCheckbox)
+	resolver := newStubResolver(map[string][]string{
+		"one.example.com": {"10.0.0.1"},
+		"two.example.com": {"10.0.0.1", "10.0.0.2"},
+	})
+	for _, test := range []struct {
+		content string
+		label   string
+		tooltip string
+	}{
+		{"#nslookup one.example.com\n10.0.0.1 one.example.com\n", "10.0.0.1 (" + checked + ")", "is the only valid\naddress for domain"},
+		{"#nslookup two.example.com\n10.0.0.2 two.example.com\n", "10.0.0.2 (" + checked + ")", "is 1 of 2 valid addresses"},
+		{"#nslookup one.example.com\n10.0.0.9 one.example.com\n", "10.0.0.9 (!)", "address not associated\nwith lookup domain"},
+		{"#nslookup none.example.com\n10.0.0.9 none.example.com\n", "10.0.0.9 (!)", "lookup none.example.com: no such host"},
+		{"10.0.0.9 plain.example.com\n", "(10.0.0.9)", "is a valid IP address"},
+	} {
+		eh := mustParse(t, test.content)
+		host := eh.Hosts()[0]
+		label, tooltip := host.GetActualInfoWith(context.Background(), resolver)
+		if label != test.label || tooltip != test.tooltip {
+			t.Errorf("GetActualInfoWith(%q) = %q, %q, want %q, %q", test.content, label, tooltip, test.label, test.tooltip)
+		}
+	}
+}
+
+func TestDNSResolver(t *testing.T) {
+	if r := NewResolver("10.0.0.53", time.Second, time.Minute); r.Server != "10.0.0.53:53" {
+		t.Errorf("NewResolver() server = %v, want 10.0.0.53:53", r.Server)
+	}
+	if r := NewResolver("[2001:db8::53]:5353", time.Second, time.Minute); r.Server != "[2001:db8::53]:5353" {
+		t.Errorf("NewResolver() server = %v, want [2001:db8::53]:5353", r.Server)
+	}
+
+	// the resolver dials a server which is never reached while the cached
+	// answer is still fresh
+	r := NewResolver("127.0.0.1:1", time.Second, time.Minute)
+	want := parseIPs("10.0.0.1")
+	r.cache["cached.example"] = resolverAnswer{found: want, expires: time.Now().Add(time.Minute)}
+	if found, err := r.LookupIP(context.Background(), "cached.example"); err != nil || !reflect.DeepEqual(found, want) {
+		t.Errorf("LookupIP() = %v, %v, want the cached %v", found, err, want)
+	}
+	r.Forget()
+	if len(r.cache) != 0 {
+		t.Errorf("Forget() left %d cached answers", len(r.cache))
+	}
+}
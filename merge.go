@@ -0,0 +1,293 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"fmt"
+	"os"
+)
+
+type MergeResolution uint8
+
+const (
+	MergeUnresolved MergeResolution = iota
+	MergeKeepOurs
+	MergeKeepTheirs
+)
+
+// MergeConflict is an entry that was changed both in memory (ours) and on
+// disk (theirs) since the file was parsed. Ours or Theirs is nil when that
+// side removed the entry
+type MergeConflict struct {
+	Base   HostInfo
+	Ours   *Host
+	Theirs *Host
+
+	resolution MergeResolution
+}
+
+// Resolve chooses which side of the conflict to keep
+func (mc *MergeConflict) Resolve(resolution MergeResolution) {
+	mc.resolution = resolution
+}
+
+// Resolution returns the choice made with Resolve
+func (mc *MergeConflict) Resolution() MergeResolution {
+	return mc.resolution
+}
+
+// mergeItem is one entry of the merged result, either a host or a conflict
+type mergeItem struct {
+	host     *Host
+	conflict *MergeConflict
+	// theirs is the on-disk host this item replaces, if any
+	theirs *Host
+}
+
+// Merge is a three-way merge of the originally parsed content (base), the
+// content currently on disk (theirs) and the in-memory edits (ours). The
+// on-disk layout is kept and the in-memory changes are applied to it.
+// Entries moved in memory keep their on-disk position
+type Merge struct {
+	Conflicts []*MergeConflict
+
+	eh     *Hostfile
+	disk   *Hostfile
+	result []*mergeItem
+}
+
+// Merge reads the file at Path and prepares a three-way merge with the
+// in-memory edits. Any Conflicts must be resolved before calling Apply
+func (eh *Hostfile) Merge() (merge *Merge, err error) {
+	var disk *Hostfile
+	if disk, err = ParseFile(eh.Path); err != nil {
+		return
+	}
+
+	eh.RLock()
+	defer eh.RUnlock()
+
+	merge = &Merge{eh: eh, disk: disk}
+
+	// pair each base entry with the on-disk entry it became, if any, first
+	// those unchanged and then those modified
+	pairs := make(map[*Host]*Host)
+	pair := func(key func(host *Host) (key string, ok bool)) {
+		unpaired := make(map[string][]*Host)
+		paired := make(map[*Host]bool, len(pairs))
+		for _, theirs := range pairs {
+			paired[theirs] = true
+		}
+		for _, theirs := range disk.parsed {
+			if k, ok := key(theirs); ok && !paired[theirs] {
+				unpaired[k] = append(unpaired[k], theirs)
+			}
+		}
+		for _, base := range eh.parsed {
+			if _, found := pairs[base]; found {
+				continue
+			}
+			if k, ok := key(base); ok && len(unpaired[k]) > 0 {
+				pairs[base] = unpaired[k][0]
+				unpaired[k] = unpaired[k][1:]
+			}
+		}
+	}
+	pair(func(host *Host) (string, bool) {
+		return fmt.Sprintf("%v\x00%v", host.onlyComment, infoKey(host.original)), true
+	})
+	pair(func(host *Host) (string, bool) {
+		return mergeKey(host.original), !host.onlyComment
+	})
+
+	present := make(map[*Host]bool)
+	for _, host := range eh.hosts {
+		present[host] = true
+	}
+	baseOf := make(map[*Host]*Host)
+	for base, theirs := range pairs {
+		baseOf[theirs] = base
+	}
+
+	slots := make(map[*Host]*mergeItem)
+	for _, theirs := range disk.parsed {
+		base, paired := baseOf[theirs]
+		if !paired {
+			// added on disk
+			slots[theirs] = merge.add(&mergeItem{host: theirs})
+			continue
+		}
+		theirsChanged := !base.original.SameHostInfo(theirs.original)
+		if !present[base] {
+			// removed in memory
+			if theirsChanged {
+				merge.addConflict(base, nil, theirs)
+			}
+			continue
+		}
+		switch oursChanged := base.Changed(); {
+		case !oursChanged:
+			slots[base] = merge.add(&mergeItem{host: theirs})
+		case !theirsChanged || base.SameHostInfo(theirs.original):
+			slots[base] = merge.add(&mergeItem{host: base, theirs: theirs})
+		default:
+			slots[base] = merge.addConflict(base, base, theirs)
+		}
+	}
+
+	// entries removed on disk but changed in memory, and entries added in
+	// memory, are placed after the entry preceding them in memory, or at the
+	// start when there is none
+	isBase := make(map[*Host]bool, len(eh.parsed))
+	for _, base := range eh.parsed {
+		isBase[base] = true
+	}
+	following := make(map[*mergeItem][]*mergeItem)
+	var previous *mergeItem
+	for _, host := range eh.hosts {
+		item, found := slots[host]
+		if !found {
+			_, paired := pairs[host]
+			switch {
+			case isBase[host] && !paired && host.Changed():
+				conflict := &MergeConflict{Base: host.original, Ours: host}
+				merge.Conflicts = append(merge.Conflicts, conflict)
+				item = &mergeItem{conflict: conflict}
+			case !isBase[host] && !host.parsed():
+				item = &mergeItem{host: host}
+			default:
+				continue
+			}
+			following[previous] = append(following[previous], item)
+		}
+		previous = item
+	}
+	if len(following) > 0 {
+		result := following[nil]
+		for _, item := range merge.result {
+			result = append(result, item)
+			result = append(result, following[item]...)
+		}
+		merge.result = result
+	}
+	return
+}
+
+func (m *Merge) add(item *mergeItem) *mergeItem {
+	m.result = append(m.result, item)
+	return item
+}
+
+func (m *Merge) addConflict(base, ours, theirs *Host) *mergeItem {
+	conflict := &MergeConflict{Base: base.original, Ours: ours, Theirs: theirs}
+	m.Conflicts = append(m.Conflicts, conflict)
+	return m.add(&mergeItem{conflict: conflict, theirs: theirs})
+}
+
+// Resolved reports whether all Conflicts have been resolved
+func (m *Merge) Resolved() bool {
+	for _, conflict := range m.Conflicts {
+		if conflict.resolution == MergeUnresolved {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply replaces the in-memory hosts with the merged result. The on-disk
+// content becomes the new base, so a following Save will not report
+// ErrModified unless the file is changed again
+func (m *Merge) Apply() (err error) {
+	if !m.Resolved() {
+		return fmt.Errorf("%d merge conflicts are unresolved", len(m.Conflicts))
+	}
+
+	var info os.FileInfo
+	if info, err = os.Stat(m.disk.Path); err != nil {
+		return
+	}
+
+	var hosts []*Host
+	parsed := append([]*Host{}, m.disk.parsed...)
+	positions := make(map[*Host]int, len(parsed))
+	for idx, h := range parsed {
+		positions[h] = idx
+	}
+	for _, item := range m.result {
+		host, theirs := item.host, item.theirs
+		if conflict := item.conflict; conflict != nil {
+			if conflict.resolution == MergeKeepTheirs {
+				host, theirs = conflict.Theirs, nil
+			} else {
+				host = conflict.Ours
+			}
+		}
+		if host == nil {
+			continue
+		}
+		if theirs != nil && host != theirs {
+			host.adopt(theirs)
+			if idx, found := positions[theirs]; found {
+				parsed[idx] = host
+			}
+		} else if _, isDisk := positions[host]; theirs == nil && host.parsed() && !isDisk {
+			// kept although removed on disk, so this is now a new entry
+			host.Lock()
+			host.leading, host.source = nil, nil
+			host.Unlock()
+		}
+		hosts = append(hosts, host)
+	}
+
+	m.eh.Lock()
+	defer m.eh.Unlock()
+	m.eh.setHosts(hosts)
+	m.eh.parsed = parsed
+	m.eh.header = m.disk.header
+	m.eh.trailing = m.disk.trailing
+	m.eh.noFinalNewline = m.disk.noFinalNewline
+	m.eh.lineEnd = m.disk.lineEnd
+	m.eh.loaded = m.disk.Path
+	m.eh.modTime = info.ModTime()
+	m.eh.checksum = m.disk.checksum
+	return
+}
+
+// adopt makes h a modified version of the other, parsed, host so that h is
+// written in the place and layout of other
+func (h *Host) adopt(other *Host) {
+	other.RLock()
+	original, format := other.original, other.format
+	leading, source := other.leading, other.source
+	other.RUnlock()
+	h.Lock()
+	h.original, h.format = original, format
+	h.leading, h.source = leading, source
+	h.Unlock()
+}
+
+// infoKey is the same for HostInfo which are the SameHostInfo
+func infoKey(info HostInfo) string {
+	return fmt.Sprintf("%v\x00%q\x00%q\x00%q\x00%q\x00%q\x00%q",
+		info.active, info.lookup, info.address, info.comment, info.trailing, info.policy, info.domains)
+}
+
+// mergeKey identifies a host entry which was modified rather than replaced
+func mergeKey(info HostInfo) string {
+	if len(info.domains) > 0 {
+		return info.domains[0]
+	}
+	return info.address
+}
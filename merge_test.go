@@ -0,0 +1,172 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n10.0.0.2 beta\n"
+	for _, test := range []struct {
+		name      string
+		disk      string
+		edit      func(t *testing.T, eh *Hostfile)
+		conflicts int
+		resolve   MergeResolution
+		want      string
+	}{
+		{
+			name: "added on disk, edited in memory",
+			disk: base + "10.0.0.3 gamma\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				hostWithDomain(t, eh, "alpha").SetAddress("10.0.0.9")
+			},
+			want: "# hosts\n127.0.0.1 localhost\n10.0.0.9 alpha\n10.0.0.2 beta\n10.0.0.3 gamma\n",
+		},
+		{
+			name: "added in memory after an entry",
+			disk: base + "10.0.0.3 gamma\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				eh.InsertHost(NewHost("10.0.0.4", "delta"), eh.IndexOf(hostWithDomain(t, eh, "alpha"))+1)
+			},
+			want: "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n\n10.0.0.4\tdelta\n10.0.0.2 beta\n10.0.0.3 gamma\n",
+		},
+		{
+			name: "modified on disk only",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha alpha.lan\n10.0.0.2 beta\n",
+			edit: func(t *testing.T, eh *Hostfile) {},
+			want: "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha alpha.lan\n10.0.0.2 beta\n",
+		},
+		{
+			name: "same change on both sides",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.9 alpha\n10.0.0.2 beta\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				hostWithDomain(t, eh, "alpha").SetAddress("10.0.0.9")
+			},
+			want: "# hosts\n127.0.0.1 localhost\n10.0.0.9 alpha\n10.0.0.2 beta\n",
+		},
+		{
+			name: "changed on both sides, keep ours",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.8 alpha\n10.0.0.2 beta\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				hostWithDomain(t, eh, "alpha").SetAddress("10.0.0.9")
+			},
+			conflicts: 1,
+			resolve:   MergeKeepOurs,
+			want:      "# hosts\n127.0.0.1 localhost\n10.0.0.9 alpha\n10.0.0.2 beta\n",
+		},
+		{
+			name: "changed on both sides, keep theirs",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.8 alpha\n10.0.0.2 beta\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				hostWithDomain(t, eh, "alpha").SetAddress("10.0.0.9")
+			},
+			conflicts: 1,
+			resolve:   MergeKeepTheirs,
+			want:      "# hosts\n127.0.0.1 localhost\n10.0.0.8 alpha\n10.0.0.2 beta\n",
+		},
+		{
+			name: "removed on disk, changed in memory, keep ours",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.2 beta\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				hostWithDomain(t, eh, "alpha").SetAddress("10.0.0.9")
+			},
+			conflicts: 1,
+			resolve:   MergeKeepOurs,
+			want:      "# hosts\n127.0.0.1 localhost\n\n10.0.0.9 alpha\n10.0.0.2 beta\n",
+		},
+		{
+			name: "removed on disk, changed in memory, keep theirs",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.2 beta\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				hostWithDomain(t, eh, "alpha").SetAddress("10.0.0.9")
+			},
+			conflicts: 1,
+			resolve:   MergeKeepTheirs,
+			want:      "# hosts\n127.0.0.1 localhost\n10.0.0.2 beta\n",
+		},
+		{
+			name: "removed in memory, changed on disk, keep ours",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n10.0.0.2 beta beta.lan\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				eh.RemoveHost(hostWithDomain(t, eh, "beta"))
+			},
+			conflicts: 1,
+			resolve:   MergeKeepOurs,
+			want:      "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n",
+		},
+		{
+			name: "removed in memory, changed on disk, keep theirs",
+			disk: "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n10.0.0.2 beta beta.lan\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				eh.RemoveHost(hostWithDomain(t, eh, "beta"))
+			},
+			conflicts: 1,
+			resolve:   MergeKeepTheirs,
+			want:      "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n10.0.0.2 beta beta.lan\n",
+		},
+		{
+			name: "removed in memory, unchanged on disk",
+			disk: base + "10.0.0.3 gamma\n",
+			edit: func(t *testing.T, eh *Hostfile) {
+				eh.RemoveHost(hostWithDomain(t, eh, "beta"))
+			},
+			want: "# hosts\n127.0.0.1 localhost\n10.0.0.1 alpha\n10.0.0.3 gamma\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts")
+			if err := os.WriteFile(path, []byte(base), 0644); err != nil {
+				t.Fatal(err)
+			}
+			eh, err := ParseFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.edit(t, eh)
+			if err = os.WriteFile(path, []byte(test.disk), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			merge, err := eh.Merge()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(merge.Conflicts) != test.conflicts {
+				t.Fatalf("got %d conflicts, want %d", len(merge.Conflicts), test.conflicts)
+			}
+			if test.conflicts > 0 {
+				if err = merge.Apply(); err == nil {
+					t.Errorf("Apply with unresolved conflicts did not fail")
+				}
+			}
+			for _, conflict := range merge.Conflicts {
+				conflict.Resolve(test.resolve)
+			}
+			if err = merge.Apply(); err != nil {
+				t.Fatal(err)
+			}
+			if got := eh.String(); got != test.want {
+				t.Errorf("merged %q, want %q", got, test.want)
+			}
+			if modified, err := eh.Modified(); err != nil || modified {
+				t.Errorf("Modified() after Apply = %v, %v", modified, err)
+			}
+		})
+	}
+}
@@ -15,10 +15,13 @@
 package editor
 
 import (
+	"crypto/sha256"
+	"io"
+	"os"
 	"regexp"
 	"strings"
 
-	"github.com/go-curses/cdk/lib/paths"
+	cstrings "github.com/go-curses/cdk/lib/strings"
 	"github.com/go-curses/cdk/log"
 )
 
@@ -27,32 +30,101 @@ const (
 )
 
 var (
-	rxCommentLine = regexp.MustCompile(`^\s*#+\s*([^#].+?)\s*$`)
-	rxLookupLine  = regexp.MustCompile(`^\s*#nslookup ([a-zA-Z][-_.a-zA-Z\d]+?)\s*$`)
+	rxCommentLine = regexp.MustCompile(`^\s*#+\s*([^#\s].*?)\s*$`)
+	rxLookupLine  = regexp.MustCompile(`^\s*#nslookup ([a-zA-Z][-_.a-zA-Z\d]+?)(?:\s+([a-z][a-z\d]*))?\s*$`)
 	rxUnHostLine  = regexp.MustCompile(`^\s*#+\s*([:a-f\d][:.a-fA-F\d]+?)\s+(.+?)\s*$`)
 	rxHostLine    = regexp.MustCompile(`^\s*([^#][:.a-fA-F\d]+?)\s+(.+?)\s*$`)
+	rxBannerLine  = regexp.MustCompile(`^\s*#+\s*$`)
 	rxEmptyLine   = regexp.MustCompile(`^\s*$`)
 	rxSpaceSep    = regexp.MustCompile(`\s+`)
 	rxNewlines    = regexp.MustCompile(`\r??\n`)
 )
 
 func ParseFile(path string) (eh *Hostfile, err error) {
-	var contents string
-	if contents, err = paths.ReadFile(path); err != nil {
+	eh, _, err = ParseFileWithReport(path)
+	return
+}
+
+// ParseFileWithReport is like ParseFile and also returns the Diagnostics for
+// any lines that were not understood or that look incorrect
+func ParseFileWithReport(path string) (eh *Hostfile, report Diagnostics, err error) {
+	var fh *os.File
+	if fh, err = os.Open(path); err != nil {
+		return
+	}
+	defer func() { _ = fh.Close() }()
+	var info os.FileInfo
+	if info, err = fh.Stat(); err != nil {
+		return
+	}
+	if eh, report, err = ParseWithReport(fh); err == nil {
+		eh.Path = path
+		eh.loaded = path
+		eh.modTime = info.ModTime()
+	}
+	return
+}
+
+// Parse reads hosts file content from r. The returned Hostfile has no Path
+// and must be given one before calling Save
+func Parse(r io.Reader) (eh *Hostfile, err error) {
+	eh, _, err = ParseWithReport(r)
+	return
+}
+
+// ParseWithReport is like Parse and also returns the Diagnostics for any
+// lines that were not understood or that look incorrect
+func ParseWithReport(r io.Reader) (eh *Hostfile, report Diagnostics, err error) {
+	var data []byte
+	if data, err = io.ReadAll(r); err != nil {
 		return
 	}
 	eh = new(Hostfile)
-	eh.Path = path
 	eh.hosts = make([]*Host, 0)
-	lines := strings.Split(contents, "\n")
-	if lines[0] == eheditorFileHeading {
-		if err := parseOwnFile(lines[1:], eh); err != nil {
-			return nil, err
+	eh.checksum = sha256.Sum256(data)
+	lines, finalNewline := splitLines(string(data))
+	eh.noFinalNewline = !finalNewline
+	eh.lineEnd = lineEnding(lines)
+	if len(lines) > 0 && lines[0] == eheditorFileHeading {
+		eh.header = lines[:1]
+		if report, err = parseOwnFile(lines[1:], 1, eh); err != nil {
+			return nil, report, err
 		}
-	} else if err := parseOtherFile(lines, eh); err != nil {
-		return nil, err
+	} else if report, err = parseOtherFile(lines, 0, eh); err != nil {
+		return nil, report, err
+	}
+	eh.setHosts(eh.hosts)
+	eh.parsed = append([]*Host{}, eh.hosts...)
+	return
+}
+
+// checkHostLine reports problems with the address and domains parsed from
+// the given host line
+func checkHostLine(number int, line string, info HostInfo) (report Diagnostics) {
+	severity := SeverityWarning
+	if !info.active {
+		// commented out lines are often just notes
+		severity = SeverityInfo
+	}
+	if !cstrings.StringIsIP(info.address) {
+		d := newDiagnostic(severity, number, line, "%q is not a valid IP address", info.address)
+		d.Column = strings.Index(line, info.address) + 1
+		report = append(report, d)
+	}
+	if len(info.domains) == 0 {
+		report = append(report, newDiagnostic(severity, number, line, "no domain names given for %v", info.address))
+	}
+	return
+}
+
+// checkLookupLine reports problems with the #nslookup line
+func checkLookupLine(number int, line string, info HostInfo) (report Diagnostics) {
+	if !info.policy.Valid() {
+		d := newDiagnostic(SeverityWarning, number, line, "unknown lookup policy %q, ignored", info.policy)
+		d.Column = strings.LastIndex(line, string(info.policy)) + 1
+		report = append(report, d)
 	}
-	return eh, nil
+	return
 }
 
 func processCommentBlocks(eh *Hostfile) (err error) {
@@ -64,17 +136,48 @@ func processCommentBlocks(eh *Hostfile) (err error) {
 	return
 }
 
-func parseOtherFile(lines []string, eh *Hostfile) (err error) {
+func parseOtherFile(lines []string, offset int, eh *Hostfile) (report Diagnostics, err error) {
 	var current *Host
+	var pending []string
+
+	// lookup is a #nslookup line directly preceding a host line
+	var lookup *HostInfo
+	var lookupLine string
+
+	appendHost := func(line string, info HostInfo) {
+		var source []string
+		if lookup != nil {
+			info.lookup, info.policy = lookup.lookup, lookup.policy
+			source = append(source, lookupLine)
+			lookup = nil
+		}
+		host := NewHostFromInfo(info)
+		host.format = defaultLineFormat.withHostLine(line)
+		host.leading, pending = pending, nil
+		host.source = append(source, line)
+		eh.hosts = append(eh.hosts, host)
+	}
+
+	for idx, line := range lines {
+		number := offset + idx + 1
 
-	for _, line := range lines {
+		if m := rxLookupLine.FindAllStringSubmatch(line, -1); m != nil && idx+1 < len(lines) &&
+			(rxHostLine.MatchString(lines[idx+1]) || rxUnHostLine.MatchString(lines[idx+1])) {
+			current = nil
+			lookup = &HostInfo{lookup: m[0][1], policy: LookupPolicy(m[0][2])}
+			lookupLine = line
+			report = append(report, checkLookupLine(number, line, *lookup)...)
+			log.DebugF("line: \"%v\", lookup: %v", line, lookup)
+			continue
+		}
 
 		if m := rxHostLine.FindAllStringSubmatch(line, -1); m != nil {
 			current = nil
 			host := HostInfo{address: m[0][1]}
-			host.domains = rxSpaceSep.Split(m[0][2], -1)
+			host.domains, host.trailing = parseDomains(m[0][2])
 			host.active = true
-			eh.hosts = append(eh.hosts, NewHostFromInfo(host))
+			appendHost(line, host)
+			report = append(report, checkHostLine(number, line, host)...)
 			log.DebugF("line: \"%v\", host: %v", line, host)
 			continue
 		}
@@ -82,9 +185,10 @@ func parseOtherFile(lines []string, eh *Hostfile) (err error) {
 		if m := rxUnHostLine.FindAllStringSubmatch(line, -1); m != nil {
 			current = nil
 			host := HostInfo{address: m[0][1]}
-			host.domains = rxSpaceSep.Split(m[0][2], -1)
+			host.domains, host.trailing = parseDomains(m[0][2])
 			host.active = false
-			eh.hosts = append(eh.hosts, NewHostFromInfo(host))
+			appendHost(line, host)
+			report = append(report, checkHostLine(number, line, host)...)
 			log.DebugF("line: \"%v\", unhost: %v", line, host)
 			continue
 		}
@@ -92,43 +196,87 @@ func parseOtherFile(lines []string, eh *Hostfile) (err error) {
 		if m := rxCommentLine.FindAllStringSubmatch(line, -1); m != nil {
 			if current == nil {
 				current = NewComment(m[0][1])
+				current.format.banner = false
+				current.format.comment = parseCommentMarker(line)
+				if last := len(pending) - 1; last >= 0 && rxBannerLine.MatchString(pending[last]) {
+					// the banner opening this comment block is part of it
+					current.format.banner = true
+					current.source = append(current.source, pending[last])
+					pending = pending[:last]
+				}
+				current.leading, pending = pending, nil
 				eh.hosts = append(eh.hosts, current)
 			} else {
 				current.AppendComment(m[0][1])
+				current.original.comment = current.comment
 			}
+			current.source = append(current.source, line)
 			log.DebugF("line: \"%v\", comment: %v", line, current)
 			continue
 		}
 
-		if line != "" {
+		if current != nil && rxBannerLine.MatchString(line) {
+			current.source = append(current.source, line)
+			current = nil
+			continue
+		}
+
+		current = nil
+		pending = append(pending, line)
+		if !rxEmptyLine.MatchString(line) && !rxBannerLine.MatchString(line) {
+			report = append(report, newDiagnostic(SeverityWarning, number, line, "unrecognized line, kept as-is"))
 			log.DebugF("skipping line: \"%v\"", line)
 		}
 	}
 
-	return processCommentBlocks(eh)
+	eh.trailing = pending
+	err = processCommentBlocks(eh)
+	return
 }
 
-func parseOwnFile(lines []string, eh *Hostfile) (err error) {
+func parseOwnFile(lines []string, offset int, eh *Hostfile) (report Diagnostics, err error) {
 	var current *HostInfo = nil
+	var format lineFormat
+	var source, pending []string
+
+	flush := func() {
+		if current != nil {
+			host := NewHostFromInfo(*current)
+			host.format = format
+			host.leading, pending = pending, nil
+			host.source = source
+			eh.hosts = append(eh.hosts, host)
+		} else {
+			pending = append(pending, source...)
+		}
+		current, source = nil, nil
+		format = defaultLineFormat
+		format.banner = false
+	}
+	flush()
+
+	for idx, line := range lines {
+		number := offset + idx + 1
 
-	for _, line := range lines {
 		if rxEmptyLine.MatchString(line) {
-			if current != nil {
-				eh.hosts = append(eh.hosts, NewHostFromInfo(*current))
-				current = nil
-			}
+			flush()
+			pending = append(pending, line)
 			log.DebugF("empty: \"%v\", current: %v", line, current)
 			continue
 		}
 
+		source = append(source, line)
+
 		if m := rxHostLine.FindAllStringSubmatch(line, -1); m != nil {
 			if current == nil {
 				current = &HostInfo{address: m[0][1]}
 			} else {
 				current.address = m[0][1]
 			}
-			current.domains = rxSpaceSep.Split(m[0][2], -1)
+			current.domains, current.trailing = parseDomains(m[0][2])
 			current.active = true
+			format = format.withHostLine(line)
+			report = append(report, checkHostLine(number, line, *current)...)
 			log.DebugF("host: \"%v\", current: %v", line, current)
 			continue
 		}
@@ -138,6 +286,8 @@ func parseOwnFile(lines []string, eh *Hostfile) (err error) {
 				current = &HostInfo{}
 			}
 			current.lookup = m[0][1]
+			current.policy = LookupPolicy(m[0][2])
+			report = append(report, checkLookupLine(number, line, *current)...)
 			log.DebugF("lookup: \"%v\", current: %v", line, current)
 			continue
 		}
@@ -148,8 +298,10 @@ func parseOwnFile(lines []string, eh *Hostfile) (err error) {
 			} else {
 				current.address = m[0][1]
 			}
-			current.domains = rxSpaceSep.Split(m[0][2], -1)
+			current.domains, current.trailing = parseDomains(m[0][2])
 			current.active = false
+			format = format.withHostLine(line)
+			report = append(report, checkHostLine(number, line, *current)...)
 			log.DebugF("inactive: \"%v\", current: %v", line, current)
 			continue
 		}
@@ -157,6 +309,7 @@ func parseOwnFile(lines []string, eh *Hostfile) (err error) {
 		if m := rxCommentLine.FindAllStringSubmatch(line, -1); m != nil {
 			if current == nil {
 				current = &HostInfo{}
+				format.comment = parseCommentMarker(line)
 			}
 			if len(current.comment) > 0 {
 				current.comment += "\n"
@@ -166,8 +319,17 @@ func parseOwnFile(lines []string, eh *Hostfile) (err error) {
 			continue
 		}
 
+		if rxBannerLine.MatchString(line) {
+			format.banner = true
+			continue
+		}
+
+		report = append(report, newDiagnostic(SeverityWarning, number, line, "unrecognized line, kept as-is"))
 		log.WarnF("unknown line: \"%v\", current: %v\n", line, current)
 	}
 
-	return processCommentBlocks(eh)
+	flush()
+	eh.trailing = pending
+	err = processCommentBlocks(eh)
+	return
 }
@@ -0,0 +1,219 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	example, err := os.ReadFile("example.etc-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"example", string(example)},
+		{"debian", "127.0.0.1\tlocalhost\n127.0.1.1\tdebian\n\n# The following lines are desirable for IPv6 capable hosts\n::1     localhost ip6-localhost ip6-loopback\nff02::1 ip6-allnodes\nff02::2 ip6-allrouters\n"},
+		{"fedora", "127.0.0.1   localhost localhost.localdomain localhost4 localhost4.localdomain4\n::1         localhost localhost.localdomain localhost6 localhost6.localdomain6\n"},
+		{"aligned columns", "10.0.0.1        alpha\t\t# the first\n10.0.0.22       beta    gamma\n  10.0.0.3\tdelta\n"},
+		{"commented out hosts", "#10.0.0.1 alpha\n# 10.0.0.2 beta\n##10.0.0.3 gamma # why\n"},
+		{"banners and blank lines", "###\n#\n###   \n\n\n10.0.0.1 alpha\n\n\n\n# end\n\n"},
+		{"unknown lines", "just some text\n10.0.0.1 alpha\n:: weird ::\n\t\n#nslookup\n"},
+		{"lookups", "#nslookup example.com\n10.0.0.1 example\n#nslookup example.org first\n10.0.0.2 org\n"},
+		{"no final newline", "10.0.0.1 alpha\n# the end"},
+		{"crlf", "10.0.0.1 alpha\r\n# note\r\n10.0.0.2 beta\r\n"},
+		{"crlf banners and lookups", "###\r\n# note\r\n###\r\n\r\n#nslookup example.com\r\n10.0.0.1 example # why\r\n"},
+		{"mixed line endings", "10.0.0.1 alpha\r\n10.0.0.2 beta\n\n#10.0.0.3 gamma\r\n"},
+		{"own format", eheditorFileHeading + "\n\n# a comment\n10.0.0.1 alpha\n\n#nslookup example.com\n10.0.0.2 beta\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			if got := eh.String(); got != test.content {
+				t.Errorf("String() = %q, want %q", got, test.content)
+			}
+		})
+	}
+}
+
+func TestParseEditChangesOnlyThatEntry(t *testing.T) {
+	content := "# hosts\n127.0.0.1\tlocalhost\n\n10.0.0.1        alpha   # first\n10.0.0.2        beta\n\n# end\n"
+	for _, test := range []struct {
+		name string
+		edit func(host *Host)
+		want string
+	}{
+		{
+			name: "domains",
+			edit: func(host *Host) { host.SetDomains("alpha gamma") },
+			want: "10.0.0.1        alpha gamma   # first",
+		},
+		{
+			name: "address",
+			edit: func(host *Host) { host.SetAddress("10.0.0.9") },
+			want: "10.0.0.9        alpha   # first",
+		},
+		{
+			name: "deactivated",
+			edit: func(host *Host) { host.SetActive(false) },
+			want: "#10.0.0.1        alpha   # first",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, content)
+			test.edit(hostWithDomain(t, eh, "alpha"))
+			want := strings.Replace(content, "10.0.0.1        alpha   # first", test.want, 1)
+			if got := eh.String(); got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+		})
+		t.Run(test.name+" crlf", func(t *testing.T) {
+			crlf := strings.ReplaceAll(content, "\n", "\r\n")
+			eh := mustParse(t, crlf)
+			test.edit(hostWithDomain(t, eh, "alpha"))
+			want := strings.Replace(crlf, "10.0.0.1        alpha   # first", test.want, 1)
+			if got := eh.String(); got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseTrailingComment(t *testing.T) {
+	eh := mustParse(t, "10.0.0.5 db.internal # primary replica\n")
+	host := hostWithDomain(t, eh, "db.internal")
+	if domains := host.Domains(); len(domains) != 1 || domains[0] != "db.internal" {
+		t.Errorf("Domains() = %q, want [db.internal]", domains)
+	} else if trailing := host.TrailingComment(); trailing != "primary replica" {
+		t.Errorf("TrailingComment() = %q, want %q", trailing, "primary replica")
+	}
+
+	host.SetAddress("10.0.0.6")
+	if got, want := eh.String(), "10.0.0.6 db.internal # primary replica\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	host.SetTrailingComment("demoted")
+	if hosts := eh.HostsForAddress("10.0.0.6"); len(hosts) != 1 || hosts[0] != host {
+		t.Errorf("HostsForAddress(10.0.0.6) = %v after editing the trailing comment", hosts)
+	} else if got, want := eh.String(), "10.0.0.6 db.internal # demoted\n"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseCRLFNewEntries(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "crlf",
+			content: "127.0.0.1 localhost\r\n",
+			want:    "127.0.0.1 localhost\r\n\r\n###\r\n# added\r\n###\r\n\r\n10.0.0.1\tnew\r\n",
+		},
+		{
+			name:    "mostly lf",
+			content: "127.0.0.1 localhost\r\n10.0.0.2 other\n10.0.0.3 more\n",
+			want:    "127.0.0.1 localhost\r\n10.0.0.2 other\n10.0.0.3 more\n\n###\n# added\n###\n\n10.0.0.1\tnew\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			eh.InsertHost(NewComment("added"), -1)
+			eh.InsertHost(NewHost("10.0.0.1", "new"), -1)
+			if got := eh.String(); got != test.want {
+				t.Errorf("String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	for _, test := range []struct {
+		line    string
+		comment string
+	}{
+		{"#x", "x"},
+		{"# x", "x"},
+		{"##  a note  ", "a note"},
+		{"  # indented", "indented"},
+		{"# 1", "1"},
+	} {
+		eh, report, err := ParseWithReport(strings.NewReader(test.line + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(report) != 0 {
+			t.Errorf("%q reported %v", test.line, report)
+		}
+		if hosts := eh.Hosts(); len(hosts) != 1 || !hosts[0].IsOnlyComment() || hosts[0].Comment() != test.comment {
+			t.Errorf("%q parsed as %v, want the comment %q", test.line, hosts, test.comment)
+		}
+	}
+}
+
+func TestDiagnosticsFormat(t *testing.T) {
+	_, report, err := ParseWithReport(strings.NewReader("# 10.0.0 alpha\n10.0.0.1 # note\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "hosts:1:3: info: \"10.0.0\" is not a valid IP address\n" +
+		"hosts:2:1: warning: no domain names given for 10.0.0.1\n"
+	if got := report.Format("hosts"); got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	} else if worst := report.Worst(); worst != SeverityWarning {
+		t.Errorf("Worst() = %v, want warning", worst)
+	} else if worst = (Diagnostics{}).Worst(); worst != SeverityInfo {
+		t.Errorf("Worst() of no diagnostics = %v, want info", worst)
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"clean", "127.0.0.1 localhost\n# a comment\n#x\n#\n", nil},
+		{"invalid address", "127.0.0.1 localhost\n  10.0.0 alpha\n", []string{"2:3: warning: \"10.0.0\" is not a valid IP address"}},
+		{"inactive invalid address", "# 10.0.0 alpha\n", []string{"1:3: info: \"10.0.0\" is not a valid IP address"}},
+		{"no domains", "10.0.0.1 # only a comment\n", []string{"1:1: warning: no domain names given for 10.0.0.1"}},
+		{"unknown lookup policy", "#nslookup example.com nearest\n10.0.0.1 example\n", []string{"1:23: warning: unknown lookup policy \"nearest\", ignored"}},
+		{"unrecognized", "10.0.0.1 alpha\n\tjust some text\n", []string{"2:2: warning: unrecognized line, kept as-is"}},
+		{"several", "bad\n10.0.0.1 alpha\n1.2.3 beta\n", []string{
+			"1:1: warning: unrecognized line, kept as-is",
+			"3:1: warning: \"1.2.3\" is not a valid IP address",
+		}},
+		{"own format", eheditorFileHeading + "\n\n10.0.0 alpha\n", []string{"3:1: warning: \"10.0.0\" is not a valid IP address"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, report, err := ParseWithReport(strings.NewReader(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, diagnostic := range report {
+				got = append(got, diagnostic.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("ParseWithReport() reported:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy decides which entries a hosts file requires, which entries are
+// protected from being removed, deactivated or given another address and how
+// the entries are grouped. A policy file is json or yaml:
+//
+//	required:                        # entries which must be present
+//	  - domain: localhost
+//	    address: 127.0.0.1           # expected address, used by Repair
+//	    label: IPv4 (localhost)      # how findings refer to the entry
+//	  - domain: myhost.example.com
+//	    family: ipv4                 # any ipv4 (or ipv6) address will do
+//	protected: [localhost]           # domains of the locked entries, the
+//	                                 # required domains when not given
+//	groups:                          # the first group with a domain of a
+//	  - name: ipv4                   # host is the Importance of the host
+//	    label: IPv4 localhost
+//	    domains: [localhost]
+type Policy struct {
+	Required  []Requirement `json:"required" yaml:"required"`
+	Protected []string      `json:"protected" yaml:"protected"`
+	Groups    []PolicyGroup `json:"groups" yaml:"groups"`
+}
+
+// Requirement is a domain which must be present in the hosts file, with the
+// Address or an address of the Family (ipv4 or ipv6) given, if any
+type Requirement struct {
+	Domain  string `json:"domain" yaml:"domain"`
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	Family  string `json:"family,omitempty" yaml:"family,omitempty"`
+	Label   string `json:"label,omitempty" yaml:"label,omitempty"`
+}
+
+// PolicyGroup is the Importance of the hosts with any of the Domains
+type PolicyGroup struct {
+	Name    HostImportance `json:"name" yaml:"name"`
+	Label   string         `json:"label,omitempty" yaml:"label,omitempty"`
+	Domains []string       `json:"domains" yaml:"domains"`
+}
+
+// DefaultPolicy is used by Validate, Repair, Host.Importance and
+// Host.IsProtected
+var DefaultPolicy = BuiltinPolicy()
+
+// BuiltinPolicy returns the policy for the localhost entries most systems
+// expect, all of which are protected
+func BuiltinPolicy() (policy *Policy) {
+	return &Policy{
+		Required: []Requirement{
+			{Domain: "localhost", Address: "127.0.0.1", Label: "IPv4 (localhost)"},
+			{Domain: "ip6-localhost", Address: "::1", Label: "IPv6 (localhost)"},
+			{Domain: "ip6-loopback", Address: "::1", Label: "IPv6 (loopback)"},
+			{Domain: "ip6-allnodes", Address: "ff02::1", Label: "IPv6 (all nodes)"},
+			{Domain: "ip6-allrouters", Address: "ff02::2", Label: "IPv6 (all routers)"},
+		},
+		Groups: []PolicyGroup{
+			{Name: HostIsLocalhostIPv4, Label: "IPv4 localhost", Domains: []string{"localhost"}},
+			{Name: HostIsLocalhostIPv6, Label: "IPv6 localhost", Domains: []string{"ip6-localhost", "ip6-loopback", "ip6-allnodes", "ip6-allrouters"}},
+		},
+	}
+}
+
+// DefaultPolicyPath returns the file the policy is read from by default, or
+// an empty string when there is no config directory
+func DefaultPolicyPath() (path string) {
+	if config, err := os.UserConfigDir(); err == nil {
+		path = filepath.Join(config, "eheditor", "policy.yaml")
+	}
+	return
+}
+
+// LoadPolicy reads a policy file, in json when the file has a .json
+// extension and yaml otherwise
+func LoadPolicy(path string) (policy *Policy, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+	policy = &Policy{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, policy)
+	} else {
+		err = yaml.Unmarshal(data, policy)
+	}
+	if err == nil {
+		err = policy.check()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return
+}
+
+func (p *Policy) check() (err error) {
+	for _, required := range p.Required {
+		switch {
+		case required.Domain == "":
+			return fmt.Errorf("required entry without a domain")
+		case required.Address != "" && net.ParseIP(required.Address) == nil:
+			return fmt.Errorf("required entry for %v: %q is not a valid IP address", required.Domain, required.Address)
+		case required.Family != "" && required.Family != "ipv4" && required.Family != "ipv6":
+			return fmt.Errorf("required entry for %v: family must be ipv4 or ipv6, not %q", required.Domain, required.Family)
+		}
+	}
+	for _, group := range p.Groups {
+		if group.Name == "" || group.Name == HostNotImportant {
+			return fmt.Errorf("group without a name")
+		}
+	}
+	return
+}
+
+// describe returns how findings refer to the required entry
+func (r Requirement) describe() string {
+	if r.Label != "" {
+		return r.Label
+	}
+	return r.Domain
+}
+
+// expected describes the address the required entry should have
+func (r Requirement) expected() string {
+	if r.Address != "" {
+		return r.Address
+	}
+	return "an " + r.Family + " address"
+}
+
+// matches reports whether the address is one the required entry may have
+func (r Requirement) matches(address string) bool {
+	if r.Address == "" && r.Family == "" {
+		return true
+	}
+	// net.IP holds ipv4 addresses as ipv4-mapped ipv6 ones, which are not
+	// the same address in a hosts file, so only the text tells them apart
+	ip, ipv4 := net.ParseIP(address), !strings.Contains(address, ":")
+	if r.Address != "" {
+		// the same address may be written differently, as ::1 and 0:0::1
+		return ip != nil && ip.Equal(net.ParseIP(r.Address)) && ipv4 == !strings.Contains(r.Address, ":")
+	}
+	return ip != nil && ipv4 == (r.Family == "ipv4")
+}
+
+// Group returns the group with the name given
+func (p *Policy) Group(name HostImportance) (group PolicyGroup, found bool) {
+	for _, group = range p.Groups {
+		if group.Name == name {
+			return group, true
+		}
+	}
+	return PolicyGroup{}, false
+}
+
+// importance returns the name of the first group with any of the domains,
+// ignoring case
+func (p *Policy) importance(domains []string) HostImportance {
+	for _, group := range p.Groups {
+		for _, domain := range domains {
+			for _, other := range group.Domains {
+				if strings.EqualFold(domain, other) {
+					return group.Name
+				}
+			}
+		}
+	}
+	return HostNotImportant
+}
+
+// protects reports whether any of the domains is protected, ignoring case
+func (p *Policy) protects(domains []string) bool {
+	if p.Protected == nil {
+		for _, required := range p.Required {
+			for _, domain := range domains {
+				if strings.EqualFold(domain, required.Domain) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, protected := range p.Protected {
+		for _, domain := range domains {
+			if strings.EqualFold(domain, protected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RepairEntries returns the entries Repair adds for the required domains
+// with an expected Address, with the domains of the same address together
+func (p *Policy) RepairEntries() (entries []Entry) {
+	for _, required := range p.Required {
+		if required.Address == "" {
+			continue
+		}
+		found := false
+		for idx := range entries {
+			if found = (Requirement{Address: entries[idx].Address}).matches(required.Address); found {
+				entries[idx].Domains = append(entries[idx].Domains, required.Domain)
+				break
+			}
+		}
+		if !found {
+			entries = append(entries, Entry{Address: required.Address, Domains: []string{required.Domain}, Active: true})
+		}
+	}
+	return
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	want := &Policy{
+		Required:  []Requirement{{Domain: "localhost", Address: "127.0.0.1", Label: "IPv4 (localhost)"}, {Domain: "myhost", Family: "ipv6"}},
+		Protected: []string{"router.lan"},
+		Groups:    []PolicyGroup{{Name: "lan", Label: "LAN", Domains: []string{"router.lan", "nas.lan"}}},
+	}
+	for _, test := range []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"policy.yaml", "# the policy\nrequired:\n  - domain: localhost\n    address: 127.0.0.1\n    label: IPv4 (localhost)\n  - domain: myhost\n    family: ipv6\nprotected: [router.lan]\ngroups:\n  - name: lan\n    label: LAN\n    domains:\n      - router.lan\n      - nas.lan\n", ""},
+		{"policy.json", `{"required": [{"domain": "localhost", "address": "127.0.0.1", "label": "IPv4 (localhost)"}, {"domain": "myhost", "family": "ipv6"}], "protected": ["router.lan"], "groups": [{"name": "lan", "label": "LAN", "domains": ["router.lan", "nas.lan"]}]}`, ""},
+		{"syntax.yaml", "required:\n  - domain: [localhost\n", "yaml"},
+		{"family.yaml", "required:\n  - domain: localhost\n    family: ipv5\n", "family must be ipv4 or ipv6"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.name)
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			policy, err := LoadPolicy(path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("LoadPolicy() error = %v, want %q", err, test.err)
+				}
+			} else if err != nil {
+				t.Errorf("LoadPolicy() error = %v", err)
+			} else if !reflect.DeepEqual(policy, want) {
+				t.Errorf("LoadPolicy() = %+v, want %+v", policy, want)
+			}
+		})
+	}
+}
+
+func TestRequirementMatches(t *testing.T) {
+	for _, test := range []struct {
+		required Requirement
+		address  string
+		want     bool
+	}{
+		{Requirement{Domain: "localhost"}, "10.0.0.1", true},
+		{Requirement{Domain: "localhost", Address: "127.0.0.1"}, "127.0.0.1", true},
+		{Requirement{Domain: "localhost", Address: "127.0.0.1"}, "127.0.1.1", false},
+		{Requirement{Domain: "localhost", Address: "127.0.0.1"}, "::ffff:127.0.0.1", false},
+		{Requirement{Domain: "localhost", Address: "::ffff:127.0.0.1"}, "127.0.0.1", false},
+		{Requirement{Domain: "localhost", Address: "::ffff:127.0.0.1"}, "::ffff:7f00:1", true},
+		{Requirement{Domain: "localhost", Address: "::1"}, "::1", true},
+		{Requirement{Domain: "localhost", Address: "::1"}, "0:0:0:0:0:0:0:1", true},
+		{Requirement{Domain: "localhost", Address: "::1"}, "0000::0001", true},
+		{Requirement{Domain: "localhost", Address: "::1"}, "::2", false},
+		{Requirement{Domain: "localhost", Address: "::1"}, "not-an-address", false},
+		{Requirement{Domain: "localhost", Family: "ipv4"}, "127.0.0.1", true},
+		{Requirement{Domain: "localhost", Family: "ipv4"}, "::1", false},
+		{Requirement{Domain: "localhost", Family: "ipv4"}, "::ffff:127.0.0.1", false},
+		{Requirement{Domain: "localhost", Family: "ipv6"}, "::ffff:127.0.0.1", true},
+		{Requirement{Domain: "localhost", Family: "ipv6"}, "0:0::1", true},
+		{Requirement{Domain: "localhost", Family: "ipv6"}, "127.0.0.1", false},
+		{Requirement{Domain: "localhost", Family: "ipv6"}, "", false},
+	} {
+		if got := test.required.matches(test.address); got != test.want {
+			t.Errorf("%+v.matches(%q) = %v, want %v", test.required, test.address, got, test.want)
+		}
+	}
+}
+
+func TestRepairEntries(t *testing.T) {
+	policy := &Policy{Required: []Requirement{
+		{Domain: "localhost", Address: "127.0.0.1"},
+		{Domain: "ip6-localhost", Address: "::1"},
+		{Domain: "any", Family: "ipv4"},
+		{Domain: "ip6-loopback", Address: "0:0::1"},
+		{Domain: "mapped", Address: "::ffff:127.0.0.1"},
+	}}
+	want := []Entry{
+		{Address: "127.0.0.1", Domains: []string{"localhost"}, Active: true},
+		{Address: "::1", Domains: []string{"ip6-localhost", "ip6-loopback"}, Active: true},
+		{Address: "::ffff:127.0.0.1", Domains: []string{"mapped"}, Active: true},
+	}
+	if got := policy.RepairEntries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RepairEntries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateRequiredAddressSpelling(t *testing.T) {
+	eh := mustParse(t, "127.0.0.1 localhost\n0:0:0:0:0:0:0:1 localhost ip6-localhost ip6-loopback\n"+
+		"ff02:0::1 ip6-allnodes\nFF02::2 ip6-allrouters\n")
+	for _, finding := range eh.Validate() {
+		if finding.Rule == "required" {
+			t.Errorf("Validate() = %v, the required addresses are only written differently", finding)
+		}
+	}
+}
+
+func TestPolicyDomainsIgnoreCase(t *testing.T) {
+	policy := &Policy{
+		Required: []Requirement{{Domain: "localhost", Address: "127.0.0.1"}},
+		Groups:   []PolicyGroup{{Name: "lan", Domains: []string{"router.lan"}}},
+	}
+	if got := policy.importance([]string{"Router.LAN"}); got != "lan" {
+		t.Errorf("importance() = %q, want lan", got)
+	}
+	if !policy.protects([]string{"LocalHost"}) {
+		t.Error("protects() = false for a required domain in another case")
+	}
+	policy.Protected = []string{"Router.lan"}
+	if !policy.protects([]string{"router.LAN"}) {
+		t.Error("protects() = false for a protected domain in another case")
+	}
+
+	eh := mustParse(t, "127.0.0.1 LocalHost\n::1 Localhost IP6-Localhost IP6-Loopback\n"+
+		"ff02::1 IP6-AllNodes\nff02::2 IP6-AllRouters\n")
+	for _, finding := range eh.Validate() {
+		if finding.Rule == "required" {
+			t.Errorf("Validate() = %v, the required domains are only written in another case", finding)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Preset is a named group of entries commonly added to hosts files
+type Preset struct {
+	Name    string
+	Entries []Entry
+}
+
+// Hosts returns new hosts for the entries of the preset, which are written
+// together without the blank line separating other new entries
+func (p Preset) Hosts() (hosts []*Host) {
+	for idx, entry := range p.Entries {
+		host := NewHostFromEntry(entry)
+		host.grouped = idx > 0
+		hosts = append(hosts, host)
+	}
+	return
+}
+
+// PresentIn reports whether all the domains of the preset entries are
+// already in the hosts file, with the same addresses
+func (p Preset) PresentIn(eh *Hostfile) bool {
+	for _, entry := range p.Entries {
+		if !entry.IsComment() && !entry.presentIn(eh) {
+			return false
+		}
+	}
+	return true
+}
+
+// MissingFrom returns the preset with only the entries which are not
+// already in the hosts file, see PresentIn. Comment entries are kept
+func (p Preset) MissingFrom(eh *Hostfile) (missing Preset) {
+	missing.Name = p.Name
+	for _, entry := range p.Entries {
+		if entry.IsComment() || !entry.presentIn(eh) {
+			missing.Entries = append(missing.Entries, entry)
+		}
+	}
+	return
+}
+
+func (e Entry) presentIn(eh *Hostfile) bool {
+	for _, domain := range e.Domains {
+		var found bool
+		for _, host := range eh.HostsForDomain(domain) {
+			if found = host.Address() == e.Address; found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// BuiltinPresets returns the presets eheditor knows about: the loopback
+// entries, the 127.0.1.1 entry for the hostname (when known) and the
+// entries for Docker and Kubernetes
+func BuiltinPresets() (presets []Preset) {
+	presets = append(presets, Preset{
+		Name: "IPv4/IPv6 loopback",
+		Entries: []Entry{
+			{Address: "127.0.0.1", Domains: []string{"localhost"}, Active: true},
+			{Address: "::1", Domains: []string{"localhost", "ip6-localhost", "ip6-loopback"}, Active: true},
+			{Address: "ff02::1", Domains: []string{"ip6-allnodes"}, Active: true},
+			{Address: "ff02::2", Domains: []string{"ip6-allrouters"}, Active: true},
+		},
+	})
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		presets = append(presets, Preset{
+			Name: "127.0.1.1 " + hostname,
+			Entries: []Entry{
+				{Address: "127.0.1.1", Domains: []string{hostname}, Active: true},
+			},
+		})
+	}
+	presets = append(presets, Preset{
+		Name: "Docker host.docker.internal",
+		Entries: []Entry{
+			{
+				Address: "172.17.0.1",
+				Domains: []string{"host.docker.internal"},
+				Active:  true,
+				Comment: "the docker0 bridge address of this host, for containers",
+			},
+		},
+	}, Preset{
+		Name: "Kubernetes kubernetes.docker.internal",
+		Entries: []Entry{
+			{Address: "127.0.0.1", Domains: []string{"kubernetes.docker.internal"}, Active: true},
+		},
+	})
+	return
+}
+
+// DefaultPresetsDir returns the directory LoadPresets reads user presets
+// from by default, or an empty string when there is no config directory
+func DefaultPresetsDir() (dir string) {
+	if config, err := os.UserConfigDir(); err == nil {
+		dir = filepath.Join(config, "eheditor", "presets")
+	}
+	return
+}
+
+// LoadPresets reads a preset from each of the files in dir, which are in
+// the hosts file format and named after the file without its extension.
+// Hidden files and files without entries are skipped and a dir which does
+// not exist has no presets. Files which cannot be read or have entries with
+// errors are skipped too, their errors are returned with the other presets
+func LoadPresets(dir string) (presets []Preset, err error) {
+	var files []os.DirEntry
+	if files, err = os.ReadDir(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	var errs []error
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		eh, parseErr := ParseFile(filepath.Join(dir, name))
+		if parseErr != nil {
+			errs = append(errs, fmt.Errorf("preset %v: %w", name, parseErr))
+			continue
+		}
+		entries := eh.Entries()
+		if checkErr := checkEntries(entries); checkErr != nil {
+			errs = append(errs, fmt.Errorf("preset %v: %w", name, checkErr))
+		} else if len(entries) > 0 {
+			presets = append(presets, Preset{
+				Name:    strings.TrimSuffix(name, filepath.Ext(name)),
+				Entries: entries,
+			})
+		}
+	}
+	return presets, errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPresets(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"b-lan.hosts":    "# the lan\n10.0.0.1 router.lan\n10.0.0.2 nas.lan\n",
+		"a-dev":          "127.0.0.1 app.test api.test\n",
+		"c-note.hosts":   "# just a note\n",
+		"d-bad.hosts":    "10.0.0.3 good.lan\n10.0.0 bad.lan\n",
+		".hidden.hosts":  "10.0.0.4 hidden.lan\n",
+		"f-unrecognized": "just some text\n10.0.0.7 printer.lan\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "g-dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	presets, err := LoadPresets(dir)
+	if err == nil || !strings.Contains(err.Error(), "preset d-bad.hosts: entry 2: ") {
+		t.Errorf("LoadPresets() error = %v, want an error for d-bad.hosts", err)
+	}
+	var names []string
+	for _, preset := range presets {
+		names = append(names, preset.Name)
+	}
+	if want := []string{"a-dev", "b-lan", "c-note", "f-unrecognized"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("LoadPresets() names = %q, want %q", names, want)
+	}
+
+	want := []Entry{
+		{Order: 1, Comment: "the lan"},
+		{Order: 2, Address: "10.0.0.1", Domains: []string{"router.lan"}, Active: true},
+		{Order: 3, Address: "10.0.0.2", Domains: []string{"nas.lan"}, Active: true},
+	}
+	if len(presets) > 1 && !reflect.DeepEqual(presets[1].Entries, want) {
+		t.Errorf("b-lan entries = %+v, want %+v", presets[1].Entries, want)
+	}
+
+	if presets, err := LoadPresets(filepath.Join(dir, "missing")); err != nil || len(presets) != 0 {
+		t.Errorf("LoadPresets() of a missing dir = %v, %v; want no presets", presets, err)
+	}
+}
+
+func TestPresetPresentIn(t *testing.T) {
+	preset := Preset{
+		Name: "lan",
+		Entries: []Entry{
+			{Comment: "the lan"},
+			{Address: "10.0.0.1", Domains: []string{"router.lan"}, Active: true},
+			{Address: "10.0.0.2", Domains: []string{"nas.lan", "files.lan"}, Active: true},
+		},
+	}
+	for _, test := range []struct {
+		name    string
+		content string
+		present bool
+		missing []string
+	}{
+		{"absent", "127.0.0.1 localhost\n", false, []string{"", "router.lan", "nas.lan"}},
+		{"present", "10.0.0.2 files.lan nas.lan\n10.0.0.1 router.lan\n", true, []string{""}},
+		{"present ignoring case", "10.0.0.1 Router.LAN\n10.0.0.2 nas.lan\n10.0.0.2 files.lan\n", true, []string{""}},
+		{"partly present", "10.0.0.1 router.lan\n10.0.0.2 nas.lan\n", false, []string{"", "nas.lan"}},
+		{"another address", "10.0.0.9 router.lan\n10.0.0.2 nas.lan files.lan\n", false, []string{"", "router.lan"}},
+		{"also another address", "10.0.0.9 router.lan\n10.0.0.1 router.lan\n10.0.0.2 nas.lan files.lan\n", true, []string{""}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			if present := preset.PresentIn(eh); present != test.present {
+				t.Errorf("PresentIn() = %v, want %v", present, test.present)
+			}
+			missing := preset.MissingFrom(eh)
+			var got []string
+			for _, entry := range missing.Entries {
+				if len(entry.Domains) > 0 {
+					got = append(got, entry.Domains[0])
+				} else {
+					got = append(got, "")
+				}
+			}
+			if missing.Name != preset.Name || !reflect.DeepEqual(got, test.missing) {
+				t.Errorf("MissingFrom() = %v %q, want the entries of %q", missing.Name, got, test.missing)
+			}
+		})
+	}
+}
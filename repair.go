@@ -0,0 +1,91 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+// missingRequired returns the required domains of the DefaultPolicy which
+// none of the hosts have, in the order of the policy
+func missingRequired(hosts []*Host) (missing []string) {
+	for _, required := range DefaultPolicy.Required {
+		var found bool
+		for _, host := range hosts {
+			if !host.IsOnlyComment() && host.HasDomain(required.Domain) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, required.Domain)
+		}
+	}
+	return
+}
+
+// RepairHosts returns new hosts with the RepairEntries of the DefaultPolicy
+// for the required domains which are missing, without adding them, see
+// Repair
+func (eh *Hostfile) RepairHosts() (hosts []*Host) {
+	missing := make(map[string]bool)
+	for _, domain := range missingRequired(eh.Hosts()) {
+		missing[domain] = true
+	}
+	for _, entry := range DefaultPolicy.RepairEntries() {
+		var domains []string
+		for _, domain := range entry.Domains {
+			if missing[domain] {
+				domains = append(domains, domain)
+			}
+		}
+		if len(domains) > 0 {
+			entry.Domains = domains
+			host := NewHostFromEntry(entry)
+			host.grouped = len(hosts) > 0
+			hosts = append(hosts, host)
+		}
+	}
+	return
+}
+
+// Repair adds the hosts RepairHosts returns at the top of the file, after
+// any entries of a policy group already there, and returns the hosts added
+func (eh *Hostfile) Repair() (added []*Host) {
+	if added = eh.RepairHosts(); len(added) == 0 {
+		return
+	}
+
+	eh.Lock()
+	defer eh.Unlock()
+
+	at := -1
+	for idx, host := range eh.hosts {
+		if host.IsOnlyComment() {
+			continue
+		} else if host.Importance() != HostNotImportant {
+			at = idx + 1
+			continue
+		} else if at < 0 {
+			at = idx
+		}
+		break
+	}
+	if at < 0 {
+		at = len(eh.hosts)
+	}
+
+	hosts := make([]*Host, 0, len(eh.hosts)+len(added))
+	hosts = append(hosts, eh.hosts[:at]...)
+	hosts = append(hosts, added...)
+	eh.setHosts(append(hosts, eh.hosts[at:]...))
+	return
+}
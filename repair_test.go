@@ -0,0 +1,62 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	const ipv6 = "::1\tip6-localhost ip6-loopback\nff02::1\tip6-allnodes\nff02::2\tip6-allrouters\n"
+	for _, test := range []struct {
+		name    string
+		content string
+		added   int
+		want    string
+	}{
+		{"empty", "", 4, "127.0.0.1\tlocalhost\n" + ipv6},
+		{"after leading comments", "# the hosts\n10.0.0.1 router\n", 4,
+			"# the hosts\n\n127.0.0.1\tlocalhost\n" + ipv6 + "10.0.0.1 router\n"},
+		{"after localhost", "127.0.0.1 localhost\n10.0.0.1 router\n", 3,
+			"127.0.0.1 localhost\n\n" + ipv6 + "10.0.0.1 router\n"},
+		{"after the localhost group", "# top\n127.0.0.1 localhost\n::1 ip6-localhost\n10.0.0.1 router\n", 3,
+			"# top\n127.0.0.1 localhost\n::1 ip6-localhost\n\n::1\tip6-loopback\nff02::1\tip6-allnodes\nff02::2\tip6-allrouters\n10.0.0.1 router\n"},
+		{"before other hosts", "10.0.0.1 router\n127.0.0.1 localhost\n", 3,
+			ipv6 + "10.0.0.1 router\n127.0.0.1 localhost\n"},
+		{"inactive localhost", "#127.0.0.1 localhost\n10.0.0.1 router\n", 3,
+			"#127.0.0.1 localhost\n\n" + ipv6 + "10.0.0.1 router\n"},
+		{"nothing missing", "127.0.0.1 localhost\n" + ipv6 + "10.0.0.1 router\n", 0,
+			"127.0.0.1 localhost\n" + ipv6 + "10.0.0.1 router\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			if added := eh.Repair(); len(added) != test.added {
+				t.Errorf("Repair() added %d hosts, want %d", len(added), test.added)
+			}
+			if got := eh.String(); got != test.want {
+				t.Errorf("Repair() =\n%q\nwant\n%q", got, test.want)
+			}
+			if added := eh.Repair(); len(added) != 0 {
+				t.Errorf("Repair() again added %d hosts, want none", len(added))
+			}
+			if got := eh.String(); got != test.want {
+				t.Errorf("Repair() again =\n%q\nwant\n%q", got, test.want)
+			}
+			if findings := eh.Validate(); len(findings) != 0 {
+				t.Errorf("Validate() after Repair() = %v", findingsText(findings))
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-curses/cdk/lib/sync"
+)
+
+// Resolver looks up the IP addresses of a domain name
+type Resolver interface {
+	LookupIP(ctx context.Context, name string) (found []net.IP, err error)
+}
+
+// DefaultResolver is used by PerformLookup, GetActualInfo and Refresh
+var DefaultResolver Resolver = NewResolver("", DefaultLookupTimeout, DefaultLookupTTL)
+
+const (
+	DefaultLookupTimeout = 5 * time.Second
+	DefaultLookupTTL     = 5 * time.Minute
+)
+
+// DNSResolver is a Resolver which caches answers for a fixed time to live,
+// optionally querying a specific DNS server instead of the system ones
+type DNSResolver struct {
+	// Server is the "host:port" of the DNS server to query, empty for the
+	// system configuration
+	Server string
+	// Timeout limits each lookup, zero for no limit other than the context
+	Timeout time.Duration
+	// TTL is how long answers are cached, zero disables caching
+	TTL time.Duration
+
+	resolver *net.Resolver
+	cache    map[string]resolverAnswer
+
+	sync.RWMutex
+}
+
+type resolverAnswer struct {
+	found   []net.IP
+	expires time.Time
+}
+
+// NewResolver creates a DNSResolver for the server, given as "host" or
+// "host:port", or the system configuration when server is empty
+func NewResolver(server string, timeout, ttl time.Duration) (r *DNSResolver) {
+	r = &DNSResolver{
+		Timeout:  timeout,
+		TTL:      ttl,
+		resolver: net.DefaultResolver,
+		cache:    make(map[string]resolverAnswer),
+	}
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		r.Server = server
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return
+}
+
+// LookupIP returns the cached answer for the name, if it has not expired, or
+// else looks up the name
+func (r *DNSResolver) LookupIP(ctx context.Context, name string) (found []net.IP, err error) {
+	r.RLock()
+	answer, cached := r.cache[name]
+	r.RUnlock()
+	if cached && time.Now().Before(answer.expires) {
+		return answer.found, nil
+	}
+
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	if found, err = r.resolver.LookupIP(ctx, "ip", name); err != nil {
+		return
+	}
+
+	if r.TTL > 0 {
+		r.Lock()
+		r.cache[name] = resolverAnswer{found: found, expires: time.Now().Add(r.TTL)}
+		r.Unlock()
+	}
+	return
+}
+
+// Forget removes all cached answers
+func (r *DNSResolver) Forget() {
+	r.Lock()
+	r.cache = make(map[string]resolverAnswer)
+	r.Unlock()
+}
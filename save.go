@@ -0,0 +1,255 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-curses/cdk/log"
+)
+
+const (
+	// BackupTimeFormat is the time layout appended to backup file names, a
+	// "-N" counter follows it when a backup with the same time exists
+	BackupTimeFormat = "20060102-150405"
+	// BackupInfix separates the original file name from the backup time
+	BackupInfix = ".eheditor-"
+
+	// maxBackupAttempts limits the counters tried for a backup name
+	maxBackupAttempts = 100
+)
+
+// ErrNotAtomic is wrapped by the error Save returns when the file cannot be
+// replaced atomically and Hostfile.InPlace is not set
+var ErrNotAtomic = errors.New("unable to save atomically")
+
+// saveAtomic writes the hosts file content to a temporary file in the same
+// directory as target, preserving the mode, ownership and extended attributes
+// of target, and then renames the temporary file into place. If that is not
+// possible, the content is written to target directly when InPlace is set
+// and an error wrapping ErrNotAtomic is returned otherwise
+func (eh *Hostfile) saveAtomic(target string) (err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(target); err != nil {
+		return
+	}
+
+	if eh.Backups > 0 {
+		if err = backupFile(target, info, eh.Backups); err != nil {
+			return fmt.Errorf("error making backup of %v: %w", target, err)
+		}
+	}
+
+	dir, base := filepath.Split(target)
+	var tmp *os.File
+	if tmp, err = os.CreateTemp(dir, "."+base+BackupInfix+"*.tmp"); err != nil {
+		return eh.notAtomic(target, err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = eh.WriteTo(tmp); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+
+	if err = copyAttributes(target, tmpPath, info); err != nil {
+		_ = os.Remove(tmpPath)
+		return eh.notAtomic(target, fmt.Errorf("unable to preserve attributes: %w", err))
+	}
+
+	if err = os.Rename(tmpPath, target); err != nil {
+		_ = os.Remove(tmpPath)
+		return eh.notAtomic(target, err)
+	}
+	syncDir(dir)
+	return
+}
+
+// notAtomic writes target in place when InPlace is set, and otherwise
+// returns the cause wrapped with ErrNotAtomic
+func (eh *Hostfile) notAtomic(target string, cause error) (err error) {
+	if !eh.InPlace {
+		return fmt.Errorf("%v: %w: %v", target, ErrNotAtomic, cause)
+	}
+	log.WarnF("unable to write %v atomically, writing in place: %v", target, cause)
+	return eh.saveInPlace(target)
+}
+
+// saveInPlace truncates and rewrites target, which is not atomic but does
+// not require the directory to be writable
+func (eh *Hostfile) saveInPlace(target string) (err error) {
+	var fh *os.File
+	if fh, err = os.OpenFile(target, os.O_WRONLY|os.O_TRUNC, 0); err != nil {
+		return
+	}
+	if _, err = eh.WriteTo(fh); err == nil {
+		err = fh.Sync()
+	}
+	if closeErr := fh.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
+
+// copyAttributes applies the mode, owner and extended attributes of the
+// source file to the destination file
+func copyAttributes(source, destination string, info os.FileInfo) (err error) {
+	if err = os.Chmod(destination, info.Mode().Perm()); err != nil {
+		return
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if err = os.Chown(destination, int(stat.Uid), int(stat.Gid)); err != nil {
+			return
+		}
+	}
+	return copyXattrs(source, destination)
+}
+
+// backupFile copies target to a new timestamped file alongside it and
+// removes the oldest backups so that no more than keep remain
+func backupFile(target string, info os.FileInfo, keep int) (err error) {
+	var src, dst *os.File
+	if src, err = os.Open(target); err != nil {
+		return
+	}
+	defer func() { _ = src.Close() }()
+
+	var backup string
+	stamp := target + BackupInfix + time.Now().Format(BackupTimeFormat)
+	for attempt := 1; attempt <= maxBackupAttempts; attempt++ {
+		if backup = stamp; attempt > 1 {
+			backup += "-" + strconv.Itoa(attempt)
+		}
+		// never replace an existing backup
+		if dst, err = os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm()); !errors.Is(err, fs.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(dst, src); err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	if err = copyAttributes(target, backup, info); err != nil {
+		// a backup with different attributes is still a backup
+		log.WarnF("unable to preserve attributes of %v: %v", backup, err)
+		err = nil
+	}
+
+	var backups []string
+	if backups, err = Backups(target); err != nil {
+		return
+	}
+	for idx := 0; idx < len(backups)-keep; idx++ {
+		if err = os.Remove(backups[idx]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Backups returns the paths to the backups made of target, oldest first
+func Backups(target string) (backups []string, err error) {
+	var matches []string
+	if matches, err = filepath.Glob(globEscape(target) + BackupInfix + "*"); err != nil {
+		return
+	}
+	type backup struct {
+		path    string
+		when    time.Time
+		counter int
+	}
+	var found []backup
+	for _, match := range matches {
+		if when, counter, ok := parseBackupStamp(match[len(target)+len(BackupInfix):]); ok {
+			found = append(found, backup{path: match, when: when, counter: counter})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].when.Equal(found[j].when) {
+			return found[i].when.Before(found[j].when)
+		}
+		return found[i].counter < found[j].counter
+	})
+	for _, b := range found {
+		backups = append(backups, b.path)
+	}
+	return
+}
+
+// parseBackupStamp returns the time and counter of a backup name suffix, see
+// BackupTimeFormat
+func parseBackupStamp(stamp string) (when time.Time, counter int, ok bool) {
+	var err error
+	counter = 1
+	if idx := strings.LastIndex(stamp, "-"); idx >= len(BackupTimeFormat) {
+		if counter, err = strconv.Atoi(stamp[idx+1:]); err != nil || counter < 2 {
+			return
+		}
+		stamp = stamp[:idx]
+	}
+	if when, err = time.Parse(BackupTimeFormat, stamp); err != nil {
+		return
+	}
+	return when, counter, true
+}
+
+func globEscape(path string) (escaped string) {
+	for _, r := range path {
+		switch r {
+		case '*', '?', '[', '\\':
+			escaped += "\\"
+		}
+		escaped += string(r)
+	}
+	return
+}
+
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	if fh, err := os.Open(dir); err == nil {
+		_ = fh.Sync()
+		_ = fh.Close()
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyXattrs copies all extended attributes, including any SELinux label,
+// from source to destination
+func copyXattrs(source, destination string) (err error) {
+	var size int
+	if size, err = unix.Listxattr(source, nil); err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil
+		}
+		return
+	} else if size == 0 {
+		return
+	}
+	names := make([]byte, size)
+	if size, err = unix.Listxattr(source, names); err != nil {
+		return
+	}
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		if size, err = unix.Getxattr(source, attr, nil); err != nil {
+			return
+		}
+		value := make([]byte, size)
+		if size, err = unix.Getxattr(source, attr, value); err != nil {
+			return
+		}
+		if err = unix.Setxattr(destination, attr, value[:size], 0); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseBackupStamp(t *testing.T) {
+	for _, test := range []struct {
+		stamp   string
+		counter int
+		ok      bool
+	}{
+		{"20240102-030405", 1, true},
+		{"20240102-030405-2", 2, true},
+		{"20240102-030405-12", 12, true},
+		{"20240102-030405-1", 0, false},
+		{"20240102-030405-x", 0, false},
+		{"20240102-0304", 0, false},
+		{"20240102", 0, false},
+		{"backup", 0, false},
+	} {
+		_, counter, ok := parseBackupStamp(test.stamp)
+		if ok != test.ok || (ok && counter != test.counter) {
+			t.Errorf("parseBackupStamp(%q) = %d, %v; want %d, %v", test.stamp, counter, ok, test.counter, test.ok)
+		}
+	}
+}
+
+func TestSaveKeepsEveryBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	original := "127.0.0.1\tlocalhost\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx := 0; idx < 4; idx++ {
+		eh, err := ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eh.Backups = 10
+		eh.InsertHost(NewHost("10.0.0.1", "example.test"), eh.Len())
+		if err = eh.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(backups) != 4 {
+		t.Fatalf("got %d backups, want 4: %v", len(backups), backups)
+	}
+	if data, err := os.ReadFile(backups[0]); err != nil {
+		t.Fatal(err)
+	} else if string(data) != original {
+		t.Errorf("oldest backup is %q, want the original %q", data, original)
+	}
+}
+
+func TestBackupsOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	stamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Format(BackupTimeFormat)
+	names := []string{
+		path + BackupInfix + stamp + "-10",
+		path + BackupInfix + "20240102-030404",
+		path + BackupInfix + stamp + "-2",
+		path + BackupInfix + stamp,
+	}
+	for _, name := range names {
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{names[1], names[3], names[2], names[0]}
+	if len(backups) != len(want) {
+		t.Fatalf("got %v, want %v", backups, want)
+	}
+	for idx := range want {
+		if backups[idx] != want[idx] {
+			t.Errorf("backup %d is %v, want %v", idx, backups[idx], want[idx])
+		}
+	}
+}
+
+func TestSaveUnchanged(t *testing.T) {
+	example, err := os.ReadFile("example.etc-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err = os.WriteFile(path, example, 0644); err != nil {
+		t.Fatal(err)
+	}
+	eh, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = eh.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(saved) != string(example) {
+		t.Errorf("saved %q, want the unchanged %q", saved, example)
+	}
+}
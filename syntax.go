@@ -0,0 +1,163 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"bufio"
+	"strings"
+	"unicode"
+)
+
+// lineFormat records the whitespace and comment markers used by a parsed
+// entry so that an edited entry can be written back in the same layout
+type lineFormat struct {
+	indent    string // whitespace before the first column
+	marker    string // comment marker of an inactive host line, ie: "#"
+	separator string // whitespace between the address and the domains
+	spacing   string // whitespace between each of the domains
+	inline    string // whitespace and marker before a trailing comment
+	comment   string // comment marker of comment lines, ie: "# "
+	banner    bool   // comment blocks are wrapped with "###" lines
+}
+
+var defaultLineFormat = lineFormat{
+	marker:    "#",
+	separator: "\t",
+	spacing:   " ",
+	inline:    " # ",
+	comment:   "# ",
+	banner:    true,
+}
+
+// withHostLine scans an active or inactive host line and returns a copy of
+// the format updated with the whitespace and markers found in line
+func (f lineFormat) withHostLine(line string) (format lineFormat) {
+	format = f
+	rest := line
+
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+	if end < 0 {
+		return
+	}
+	format.indent, rest = rest[:end], rest[end:]
+
+	if strings.HasPrefix(rest, "#") {
+		end = strings.IndexFunc(rest, func(r rune) bool { return r != '#' && !unicode.IsSpace(r) })
+		if end < 0 {
+			return
+		}
+		format.marker, rest = rest[:end], rest[end:]
+	}
+
+	// skip the address
+	if end = strings.IndexFunc(rest, unicode.IsSpace); end < 0 {
+		return
+	}
+	rest = rest[end:]
+	end = strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+	if end < 0 {
+		return
+	}
+	format.separator, rest = rest[:end], rest[end:]
+
+	if idx := strings.Index(rest, "#"); idx >= 0 {
+		domains := strings.TrimRightFunc(rest[:idx], unicode.IsSpace)
+		marker := rest[idx:]
+		if end = strings.IndexFunc(marker, func(r rune) bool { return r != '#' && !unicode.IsSpace(r) }); end >= 0 {
+			marker = marker[:end]
+		}
+		format.inline = rest[len(domains):idx] + marker
+		rest = domains
+	}
+
+	// the first gap between domains is the spacing for all of them
+	if end = strings.IndexFunc(rest, unicode.IsSpace); end < 0 {
+		return
+	}
+	rest = rest[end:]
+	if end = strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) }); end > 0 {
+		format.spacing = rest[:end]
+	}
+	return
+}
+
+// parseDomains splits the text following the address of a host line into
+// the list of domains and any trailing comment
+func parseDomains(text string) (domains []string, trailing string) {
+	if idx := strings.Index(text, "#"); idx >= 0 {
+		trailing = strings.TrimSpace(strings.TrimLeft(text[idx:], "#"))
+		text = text[:idx]
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		domains = rxSpaceSep.Split(text, -1)
+	}
+	return
+}
+
+// parseCommentMarker returns the leading "#" characters and any whitespace
+// that follows them in the given comment line
+func parseCommentMarker(line string) (marker string) {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	end := strings.IndexFunc(trimmed, func(r rune) bool { return r != '#' && !unicode.IsSpace(r) })
+	if end < 0 {
+		return defaultLineFormat.comment
+	}
+	return trimmed[:end]
+}
+
+// splitLines splits contents into lines, without the newline characters,
+// and reports whether the last line was terminated with a newline
+func splitLines(contents string) (lines []string, finalNewline bool) {
+	if contents == "" {
+		return nil, true
+	}
+	lines = strings.Split(contents, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		return lines[:last], true
+	}
+	return lines, false
+}
+
+// lineEnding returns "\r" when most of the lines end with one, as split
+// from content with CRLF line endings
+func lineEnding(lines []string) (end string) {
+	var crlf int
+	for _, line := range lines {
+		if strings.HasSuffix(line, "\r") {
+			crlf += 1
+		}
+	}
+	if crlf > 0 && crlf*2 >= len(lines) {
+		end = "\r"
+	}
+	return
+}
+
+// countingWriter tracks the number of bytes written and the first error
+// encountered, ignoring all writes after an error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) WriteString(s string) {
+	if cw.err != nil {
+		return
+	}
+	var written int
+	written, cw.err = cw.w.WriteString(s)
+	cw.n += int64(written)
+}
@@ -48,6 +48,24 @@ func (c *CUI) makeAccelmap() (ag ctk.AccelGroup) {
 			return
 		},
 	)
+	ag.ConnectByPath(
+		"<eheditor-window>/Edit/Undo",
+		"undo-accel",
+		func(argv ...interface{}) (handled bool) {
+			ag.LogDebug("undo-accel called")
+			c.requestUndo()
+			return
+		},
+	)
+	ag.ConnectByPath(
+		"<eheditor-window>/Edit/Redo",
+		"redo-accel",
+		func(argv ...interface{}) (handled bool) {
+			ag.LogDebug("redo-accel called")
+			c.requestRedo()
+			return
+		},
+	)
 	return
 }
 
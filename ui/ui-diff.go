@@ -0,0 +1,155 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/cdk/lib/ptypes"
+	"github.com/go-curses/cdk/log"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+)
+
+// gSaveDiffMargin is the number of screen lines the save diff dialog needs
+// besides the lines of the diff, for its borders, message and buttons
+const gSaveDiffMargin = 9
+
+func (c *CUI) newSaveDiffDialog(diff string) {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	// the file header lines only repeat the source file name
+	if len(lines) > 2 {
+		lines = lines[2:]
+	}
+	screen := ptypes.MakeRectangle(c.Display.Screen().Size())
+	visible := min(len(lines), max(screen.H-gSaveDiffMargin, 1))
+	view := newDiffView(lines, visible)
+
+	message := fmt.Sprintf("Save these changes to %v?", c.SourceFile)
+	dialog := ctk.NewYesNoDialog("Save Changes?", message, false)
+	dialog.GetContentArea().PackStart(view, false, false, 0)
+	dialog.SetSizeRequest(min(72, screen.W), visible+gSaveDiffMargin-1)
+	dialog.Connect(ctk.SignalEventKey, "save-diff-key-handler", view.processKey)
+	dialog.Connect(ctk.SignalEventMouse, "save-diff-mouse-handler", view.processMouse)
+	dialog.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
+		switch response {
+		case enums.ResponseYes:
+			c.requestSaveConfirmed()
+		default:
+			log.DebugF("user cancelled save operation")
+		}
+	})
+}
+
+// diffView shows the lines of a diff which fit in its height, scrolled with
+// the cursor keys, the mouse wheel or the scrollbar
+type diffView struct {
+	ctk.HBox
+
+	label   ctk.Label
+	scroll  ctk.VScrollbar
+	lines   []string
+	offset  int
+	visible int
+}
+
+func newDiffView(lines []string, visible int) (v *diffView) {
+	v = &diffView{lines: lines, visible: visible}
+	v.HBox = ctk.NewHBox(false, 0)
+	v.HBox.Show()
+	v.HBox.SetSizeRequest(-1, visible)
+
+	v.label = ctk.NewLabel("")
+	v.label.Show()
+	// left justification would remove the space before the context lines
+	v.label.SetJustify(cenums.JUSTIFY_NONE)
+	v.label.SetSingleLineMode(false)
+	v.label.SetLineWrap(false)
+	v.HBox.PackStart(v.label, true, true, 0)
+
+	v.scroll = ctk.NewVScrollbar()
+	v.scroll.SetRange(0, max(len(lines)-visible, 0))
+	v.scroll.SetPageSize(visible)
+	v.scroll.SetIncrements(1, max(visible, 1))
+	v.scroll.Connect(ctk.SignalValueChanged, "save-diff-scroll-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		v.ScrollTo(v.scroll.GetValue())
+		return cenums.EVENT_PASS
+	})
+	if len(lines) > visible {
+		v.scroll.Show()
+	}
+	v.HBox.PackEnd(v.scroll, false, false, 0)
+	v.ScrollTo(0)
+	return
+}
+
+// ScrollTo makes the line at offset the first one shown, within the limits
+// of the lines available
+func (v *diffView) ScrollTo(offset int) {
+	v.offset = min(max(offset, 0), max(len(v.lines)-v.visible, 0))
+	v.label.SetText(strings.Join(v.lines[v.offset:min(v.offset+v.visible, len(v.lines))], "\n"))
+	if v.scroll.GetValue() != v.offset {
+		v.scroll.SetValue(v.offset)
+	}
+}
+
+func (v *diffView) processKey(_ []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) < 2 {
+		return cenums.EVENT_PASS
+	}
+	e, ok := argv[1].(*cdk.EventKey)
+	if !ok {
+		return cenums.EVENT_PASS
+	}
+	switch e.Key() {
+	case cdk.KeyUp:
+		v.ScrollTo(v.offset - 1)
+	case cdk.KeyDown:
+		v.ScrollTo(v.offset + 1)
+	case cdk.KeyPgUp:
+		v.ScrollTo(v.offset - v.visible)
+	case cdk.KeyPgDn:
+		v.ScrollTo(v.offset + v.visible)
+	case cdk.KeyHome:
+		v.ScrollTo(0)
+	case cdk.KeyEnd:
+		v.ScrollTo(len(v.lines))
+	default:
+		return cenums.EVENT_PASS
+	}
+	return cenums.EVENT_STOP
+}
+
+func (v *diffView) processMouse(_ []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) < 2 {
+		return cenums.EVENT_PASS
+	}
+	e, ok := argv[1].(*cdk.EventMouse)
+	if !ok || !e.IsWheelImpulse() {
+		return cenums.EVENT_PASS
+	}
+	switch e.WheelImpulse() {
+	case cdk.WheelUp:
+		v.ScrollTo(v.offset - gSidebarWheelStep)
+	case cdk.WheelDown:
+		v.ScrollTo(v.offset + gSidebarWheelStep)
+	default:
+		return cenums.EVENT_PASS
+	}
+	return cenums.EVENT_STOP
+}
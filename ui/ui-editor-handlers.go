@@ -31,31 +31,47 @@ func (c *CUI) activateSidebarAddRowHandler(data []interface{}, argv ...interface
 	var entries []interface{}
 	entries = append(entries, "Comment Entry", 1)
 	entries = append(entries, "Host Entry", 2)
-	// add presets that don't already exist, ie localhost
+	// presets already present are not offered
+	width := 32
+	var presets []editor.Preset
+	for _, preset := range c.Presets {
+		if !preset.PresentIn(c.HostFile) {
+			presets = append(presets, preset)
+			entries = append(entries, preset.Name, 2+len(presets))
+			width = max(width, len(preset.Name)+6)
+		}
+	}
 
 	dialog := ctk.NewButtonMenuDialog(
 		"Add Entry",
 		"Select a type of entry to add:",
 		entries...,
 	)
-	dialog.SetSizeRequest(32, 9)
+	dialog.SetSizeRequest(width, min(len(entries)/2, 8)+7)
 	dialog.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
 		switch response {
 		case 1: // add comment
 			c.SidebarAddEntryButton.LogDebug("add comment at index: %v", idx)
 			h := editor.NewComment("")
-			c.HostFile.InsertHost(h, idx)
+			c.insertHost(h, idx)
 			c.requestReloadContents()
 			c.focusEditor(h)
 		case 2: // add host
 			c.SidebarAddEntryButton.LogDebug("add host at index: %v", idx)
 			h := editor.NewHostFromInfo(editor.HostInfo{})
-			c.HostFile.InsertHost(h, idx)
+			c.insertHost(h, idx)
 			c.requestReloadContents()
 			c.focusEditor(h)
 		default:
 			if responseId := int(response); responseId < 0 {
 				c.SidebarAddEntryButton.LogDebug("new entry action cancelled")
+			} else if responseId-3 < len(presets) {
+				preset := presets[responseId-3]
+				c.SidebarAddEntryButton.LogDebug("add preset %q at index: %v", preset.Name, idx)
+				hosts := preset.MissingFrom(c.HostFile).Hosts()
+				c.insertHosts(hosts, idx)
+				c.requestReloadContents()
+				c.focusEditor(hosts[0])
 			} else {
 				log.ErrorF("unhandled dialog response: %v", response)
 			}
@@ -76,7 +92,7 @@ func (c *CUI) activateSidebarMoveRowUpHandler(data []interface{}, argv ...interf
 	if nextIdx < 0 {
 		return cenums.EVENT_STOP
 	}
-	c.HostFile.MoveHost(thisIdx, nextIdx)
+	c.moveHost(c.SelectedHost, nextIdx)
 	c.reloadEditor()
 	c.focusEditor(c.SelectedHost)
 	return cenums.EVENT_STOP
@@ -94,7 +110,7 @@ func (c *CUI) activateSidebarMoveRowDownHandler(data []interface{}, argv ...inte
 	if nextIdx > lastIdx {
 		return cenums.EVENT_STOP
 	}
-	c.HostFile.MoveHost(thisIdx, nextIdx)
+	c.moveHost(c.SelectedHost, nextIdx)
 	c.reloadEditor()
 	c.focusEditor(c.SelectedHost)
 	return cenums.EVENT_STOP
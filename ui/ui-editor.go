@@ -16,13 +16,12 @@ package ui
 
 import (
 	"fmt"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/go-corelibs/maps"
+	"github.com/maruel/natural"
 
-	"github.com/go-curses/cdk"
 	cenums "github.com/go-curses/cdk/lib/enums"
 	"github.com/go-curses/cdk/lib/paint"
 	cstrings "github.com/go-curses/cdk/lib/strings"
@@ -38,6 +37,12 @@ const (
 	gSidebarInnerWidth = 20
 )
 
+// sidebar button badges for hosts with validation findings
+const (
+	gSidebarErrorBadge   = " !"
+	gSidebarWarningBadge = " ?"
+)
+
 func (c *CUI) switchToEditor() {
 	c.Window.Freeze()
 	c.ContentsHBox.Freeze()
@@ -50,6 +55,7 @@ func (c *CUI) switchToEditor() {
 	}
 	c.Window.Thaw()
 	c.ContentsHBox.Thaw()
+	c.reloadEditor()
 	c.focusEditor(c.SelectedHost)
 }
 
@@ -65,6 +71,7 @@ func (c *CUI) makeEditor() ctk.Widget {
 	var sidebarEntryFrame ctk.Frame
 	var sidebarLocalsFrame ctk.Frame
 	var sidebarCustomFrame ctk.Frame
+	var sidebarBlockedFrame ctk.Frame
 	var sidebarCommentsFrame ctk.Frame
 
 	changeSidebarMode := func(mode SidebarListMode) {
@@ -81,6 +88,7 @@ func (c *CUI) makeEditor() ctk.Widget {
 			sidebarCommentsFrame.Hide()
 			sidebarLocalsFrame.Hide()
 			sidebarCustomFrame.Hide()
+			sidebarBlockedFrame.Hide()
 			sidebarEntryFrame.Show()
 		case ListByAddress:
 			aWidth = gSidebarInnerWidth - 5
@@ -90,6 +98,7 @@ func (c *CUI) makeEditor() ctk.Widget {
 			sidebarCommentsFrame.Show()
 			sidebarLocalsFrame.Show()
 			sidebarCustomFrame.Show()
+			sidebarBlockedFrame.Show()
 			sidebarEntryFrame.Hide()
 		case ListByDomain:
 			dWidth = gSidebarInnerWidth - 5
@@ -99,6 +108,7 @@ func (c *CUI) makeEditor() ctk.Widget {
 			sidebarCommentsFrame.Show()
 			sidebarLocalsFrame.Show()
 			sidebarCustomFrame.Show()
+			sidebarBlockedFrame.Show()
 			sidebarEntryFrame.Hide()
 		}
 
@@ -166,6 +176,23 @@ func (c *CUI) makeEditor() ctk.Widget {
 	sidebarVBox.Show()
 	c.SidebarFrame.Add(sidebarVBox)
 
+	// list filter
+
+	c.SidebarFilterEntry = ctk.NewEntry("")
+	c.SidebarFilterEntry.SetName("sidebar-filter")
+	c.SidebarFilterEntry.Show()
+	c.SidebarFilterEntry.SetSelectable(true)
+	c.SidebarFilterEntry.SetLineWrap(false)
+	c.SidebarFilterEntry.SetSingleLineMode(true)
+	c.SidebarFilterEntry.SetSizeRequest(-1, 1)
+	c.SidebarFilterEntry.SetHasTooltip(true)
+	c.SidebarFilterEntry.SetTooltipText("Press / to filter by domain, address or comment, Escape to clear")
+	c.SidebarFilterEntry.Connect(ctk.SignalChangedText, "sidebar-filter-changed-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		c.setSidebarFilter(c.SidebarFilterEntry.GetText())
+		return cenums.EVENT_STOP
+	})
+	sidebarVBox.PackStart(c.SidebarFilterEntry, false, false, 0)
+
 	// list toggles
 
 	toggleLocals := ctk.NewButtonWithLabel(string(paint.RuneTriangleRight) + " locals")
@@ -174,61 +201,60 @@ func (c *CUI) makeEditor() ctk.Widget {
 	toggleCustom := ctk.NewButtonWithLabel(string(paint.RuneTriangleDown) + " custom")
 	toggleCustom.Show()
 	toggleCustom.SetTheme(SidebarHeaderTheme)
+	toggleBlocked := ctk.NewButtonWithLabel(string(paint.RuneTriangleRight) + " blocked")
+	toggleBlocked.Show()
+	toggleBlocked.SetTheme(SidebarHeaderTheme)
 	toggleComments := ctk.NewButtonWithLabel(string(paint.RuneTriangleRight) + " comments")
 	toggleComments.Show()
 	toggleComments.SetTheme(SidebarHeaderTheme)
 
-	toggleLocals.Connect(ctk.SignalActivate, "locals-toggle-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
-		toggleLocals.SetLabel(string(paint.RuneTriangleDown) + " locals")
-		toggleCustom.SetLabel(string(paint.RuneTriangleRight) + " custom")
-		toggleComments.SetLabel(string(paint.RuneTriangleRight) + " comments")
-		sidebarLocalsFrame.SetSizeRequest(-1, -1)
-		sidebarCustomFrame.SetSizeRequest(-1, 1)
-		sidebarCommentsFrame.SetSizeRequest(-1, 1)
-		c.SidebarLocalsList.SetSizeRequest(gSidebarInnerWidth, -1)
-		c.SidebarCustomList.SetSizeRequest(gSidebarInnerWidth, 0)
-		c.SidebarCommentsList.SetSizeRequest(gSidebarInnerWidth, 0)
+	// toggleListSection expands the named list section and collapses the
+	// others
+	toggleListSection := func(name string) {
+		for _, section := range []struct {
+			name   string
+			toggle ctk.Button
+			frame  ctk.Frame
+			list   *SidebarList
+		}{
+			{"locals", toggleLocals, sidebarLocalsFrame, c.SidebarLocalsList},
+			{"custom", toggleCustom, sidebarCustomFrame, c.SidebarCustomList},
+			{"blocked", toggleBlocked, sidebarBlockedFrame, c.SidebarBlockedList},
+			{"comments", toggleComments, sidebarCommentsFrame, c.SidebarCommentsList},
+		} {
+			if section.name == name {
+				section.toggle.SetLabel(string(paint.RuneTriangleDown) + " " + section.name)
+				section.frame.SetSizeRequest(-1, -1)
+				section.list.SetSizeRequest(gSidebarInnerWidth, -1)
+			} else {
+				section.toggle.SetLabel(string(paint.RuneTriangleRight) + " " + section.name)
+				section.frame.SetSizeRequest(-1, 1)
+				section.list.SetSizeRequest(gSidebarInnerWidth, 0)
+			}
+		}
 		c.Window.Resize()
 		c.Window.ReApplyStyles()
 		c.Display.RequestDraw()
 		c.Display.RequestShow()
-		// toggleListSection("locals")
+	}
+
+	toggleLocals.Connect(ctk.SignalActivate, "locals-toggle-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		toggleListSection("locals")
 		return cenums.EVENT_STOP
 	})
 
 	toggleCustom.Connect(ctk.SignalActivate, "custom-toggle-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
-		toggleLocals.SetLabel(string(paint.RuneRArrow) + " locals")
-		toggleCustom.SetLabel(string(paint.RuneDArrow) + " custom")
-		toggleComments.SetLabel(string(paint.RuneRArrow) + " comments")
-		sidebarLocalsFrame.SetSizeRequest(-1, 1)
-		sidebarCustomFrame.SetSizeRequest(-1, -1)
-		sidebarCommentsFrame.SetSizeRequest(-1, 1)
-		c.SidebarLocalsList.SetSizeRequest(gSidebarInnerWidth, 0)
-		c.SidebarCustomList.SetSizeRequest(gSidebarInnerWidth, -1)
-		c.SidebarCommentsList.SetSizeRequest(gSidebarInnerWidth, 0)
-		c.Window.Resize()
-		c.Window.ReApplyStyles()
-		c.Display.RequestDraw()
-		c.Display.RequestShow()
-		// toggleListSection("custom")
+		toggleListSection("custom")
 		return cenums.EVENT_STOP
 	})
 
-	toggleComments.Connect(ctk.SignalActivate, "custom-toggle-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
-		toggleLocals.SetLabel(string(paint.RuneRArrow) + " locals")
-		toggleCustom.SetLabel(string(paint.RuneRArrow) + " custom")
-		toggleComments.SetLabel(string(paint.RuneDArrow) + " comments")
-		sidebarLocalsFrame.SetSizeRequest(-1, 1)
-		sidebarCustomFrame.SetSizeRequest(-1, 1)
-		sidebarCommentsFrame.SetSizeRequest(-1, -1)
-		c.SidebarLocalsList.SetSizeRequest(gSidebarInnerWidth, 0)
-		c.SidebarCustomList.SetSizeRequest(gSidebarInnerWidth, 0)
-		c.SidebarCommentsList.SetSizeRequest(gSidebarInnerWidth, -1)
-		c.Window.Resize()
-		c.Window.ReApplyStyles()
-		c.Display.RequestDraw()
-		c.Display.RequestShow()
-		// toggleListSection("custom")
+	toggleBlocked.Connect(ctk.SignalActivate, "blocked-toggle-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		toggleListSection("blocked")
+		return cenums.EVENT_STOP
+	})
+
+	toggleComments.Connect(ctk.SignalActivate, "comments-toggle-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		toggleListSection("comments")
 		return cenums.EVENT_STOP
 	})
 
@@ -241,15 +267,9 @@ func (c *CUI) makeEditor() ctk.Widget {
 	sidebarLocalsFrame.SetLabelAlign(0.0, 0.5)
 	sidebarVBox.PackStart(sidebarLocalsFrame, false, false, 0)
 
-	sidebarLocalsScroll := ctk.NewScrolledViewport()
-	sidebarLocalsScroll.Show()
-	sidebarLocalsScroll.SetPolicy(enums.PolicyAutomatic, enums.PolicyNever)
-	sidebarLocalsFrame.Add(sidebarLocalsScroll)
-
-	c.SidebarLocalsList = ctk.NewVBox(false, 0)
-	c.SidebarLocalsList.Show()
+	c.SidebarLocalsList = c.newSidebarList("locals")
 	c.SidebarLocalsList.SetSizeRequest(gSidebarInnerWidth, -1)
-	sidebarLocalsScroll.Add(c.SidebarLocalsList)
+	sidebarLocalsFrame.Add(c.SidebarLocalsList)
 
 	// custom list
 
@@ -260,15 +280,22 @@ func (c *CUI) makeEditor() ctk.Widget {
 	sidebarCustomFrame.SetTheme(SidebarFrameTheme)
 	sidebarVBox.PackStart(sidebarCustomFrame, false, false, 0)
 
-	sidebarCustomScroll := ctk.NewScrolledViewport()
-	sidebarCustomScroll.Show()
-	sidebarCustomScroll.SetPolicy(enums.PolicyAutomatic, enums.PolicyNever)
-	sidebarCustomFrame.Add(sidebarCustomScroll)
-
-	c.SidebarCustomList = ctk.NewVBox(false, 0)
-	c.SidebarCustomList.Show()
+	c.SidebarCustomList = c.newSidebarList("custom")
 	c.SidebarCustomList.SetSizeRequest(gSidebarInnerWidth, -1)
-	sidebarCustomScroll.Add(c.SidebarCustomList)
+	sidebarCustomFrame.Add(c.SidebarCustomList)
+
+	// blocked list
+
+	sidebarBlockedFrame = ctk.NewFrameWithWidget(toggleBlocked)
+	sidebarBlockedFrame.Show()
+	sidebarBlockedFrame.SetSizeRequest(-1, 1)
+	sidebarBlockedFrame.SetLabelAlign(0.0, 0.5)
+	sidebarBlockedFrame.SetTheme(SidebarFrameTheme)
+	sidebarVBox.PackStart(sidebarBlockedFrame, false, false, 0)
+
+	c.SidebarBlockedList = c.newSidebarList("blocked")
+	c.SidebarBlockedList.SetSizeRequest(gSidebarInnerWidth, -1)
+	sidebarBlockedFrame.Add(c.SidebarBlockedList)
 
 	// comments list
 
@@ -279,15 +306,9 @@ func (c *CUI) makeEditor() ctk.Widget {
 	sidebarCommentsFrame.SetTheme(SidebarFrameTheme)
 	sidebarVBox.PackStart(sidebarCommentsFrame, false, false, 0)
 
-	sidebarCommentsScroll := ctk.NewScrolledViewport()
-	sidebarCommentsScroll.Show()
-	sidebarCommentsScroll.SetPolicy(enums.PolicyAutomatic, enums.PolicyNever)
-	sidebarCommentsFrame.Add(sidebarCommentsScroll)
-
-	c.SidebarCommentsList = ctk.NewVBox(false, 0)
-	c.SidebarCommentsList.Show()
+	c.SidebarCommentsList = c.newSidebarList("comments")
 	c.SidebarCommentsList.SetSizeRequest(gSidebarInnerWidth, -1)
-	sidebarCommentsScroll.Add(c.SidebarCommentsList)
+	sidebarCommentsFrame.Add(c.SidebarCommentsList)
 
 	// entry list
 
@@ -298,15 +319,9 @@ func (c *CUI) makeEditor() ctk.Widget {
 	sidebarEntryFrame.SetTheme(SidebarFrameTheme)
 	sidebarVBox.PackStart(sidebarEntryFrame, false, false, 0)
 
-	sidebarEntryScroll := ctk.NewScrolledViewport()
-	sidebarEntryScroll.Show()
-	sidebarEntryScroll.SetPolicy(enums.PolicyAutomatic, enums.PolicyNever)
-	sidebarEntryFrame.Add(sidebarEntryScroll)
-
-	c.SidebarEntryList = ctk.NewVBox(false, 0)
-	c.SidebarEntryList.Show()
+	c.SidebarEntryList = c.newSidebarList("entries")
 	c.SidebarEntryList.SetSizeRequest(gSidebarInnerWidth, -1)
-	sidebarEntryScroll.Add(c.SidebarEntryList)
+	sidebarEntryFrame.Add(c.SidebarEntryList)
 
 	// sidebar action buttons
 
@@ -425,6 +440,18 @@ func (c *CUI) makeEditor() ctk.Widget {
 	// c.DomainsEntry.SetJustify(cenums.JUSTIFY_LEFT)
 	c.HostEditVBox.PackStart(c.DomainsEntry, true, true, 0)
 
+	addSeparator(c.HostEditVBox)
+	addInstructions(c.HostEditVBox, "Comment at the end of the host line:")
+
+	c.TrailingEntry = ctk.NewEntry("")
+	c.TrailingEntry.SetName("editing-trailing-comment")
+	c.TrailingEntry.Show()
+	c.TrailingEntry.SetSelectable(true)
+	c.TrailingEntry.SetLineWrap(false)
+	c.TrailingEntry.SetSizeRequest(-1, 1)
+	c.TrailingEntry.SetSingleLineMode(true)
+	c.HostEditVBox.PackStart(c.TrailingEntry, false, false, 0)
+
 	addSeparator(panelVBox)
 	addInstructions(panelVBox, "Hosts file entry actions:")
 
@@ -445,7 +472,9 @@ func (c *CUI) makeEditor() ctk.Widget {
 	// panelVBox.PackStart(c.DeleteButton, true, true, 0)
 	hostActionHBox.PackStart(c.DeleteButton, true, true, 0)
 
-	changeSidebarMode(ListByDomain)
+	c.makeMarkedPanel()
+
+	changeSidebarMode(c.SidebarMode)
 
 	return c.EditingHBox
 }
@@ -460,57 +489,31 @@ func (c *CUI) reloadEditor() {
 		c.Display.RequestSync()
 	}()
 
-	purgeContents := func(v ctk.VBox) {
-		existing := v.GetChildren()
-		for _, child := range existing {
-			v.Remove(child)
-			child.Destroy()
-		}
-	}
-
-	c.EditorCommentList = []*editor.Host{}
-	purgeContents(c.SidebarEntryList)
-	purgeContents(c.SidebarLocalsList)
-	purgeContents(c.SidebarCustomList)
-	purgeContents(c.SidebarCommentsList)
-
-	c.EditorAddressLookup = make(map[string]*editor.Host)
-	c.EditorDomainsLookup = make(map[string]*editor.Host)
+	c.updateChangedButtons()
+	c.requestValidate()
+	c.updateEditor()
+}
 
-	var changed bool
-	unique := make(map[string]int)
-	for _, host := range c.HostFile.Hosts() {
-		if !changed && host.Changed() {
-			changed = true
-		}
-		if host.IsOnlyComment() {
-			c.EditorCommentList = append(c.EditorCommentList, host)
-			continue
-		}
-		c.EditorAddressLookup[host.Address()] = host
-		if domains := host.Domains(); len(domains) == 0 {
-			c.EditorDomainsLookup[""] = host
-		} else {
-			for _, domain := range domains {
-				if _, found := unique[domain]; !found {
-					unique[domain] = 1
-				} else {
-					unique[domain] += 1
-				}
-				if unique[domain] > 1 {
-					c.EditorDomainsLookup[domain+" ("+strconv.Itoa(unique[domain])+")"] = host
-				} else {
-					c.EditorDomainsLookup[domain] = host
-				}
-			}
-		}
+// refreshEditor is reloadEditor for edits which do not change how the hosts
+// are listed, only the rows shown are updated instead of rebuilding all the
+// sidebar lists
+func (c *CUI) refreshEditor() {
+	if c.SidebarFilter != "" {
+		// the edit may change which hosts match the filter
+		c.reloadEditor()
+		return
 	}
+	c.updateChangedButtons()
+	c.requestValidate()
+	c.renderSidebarLists()
+	c.Display.RequestDraw()
+	c.Display.RequestShow()
+}
 
+func (c *CUI) updateChangedButtons() {
+	changed := c.HostFile.Changed()
 	c.SaveButton.SetSensitive(changed)
 	c.ReloadButton.SetSensitive(changed)
-
-	c.updateEditor()
-
 }
 
 func (c *CUI) updateEditor() {
@@ -522,42 +525,222 @@ func (c *CUI) updateEditor() {
 	}
 }
 
+// sidebarChoices returns a row for each host with each of the domains, or
+// for each host with each of the addresses, sorted by domain or address.
+// Each row after the first of a domain or address is numbered
+func (c *CUI) sidebarChoices() (rows []SidebarRow) {
+	var keys []string
+	var hostsFor func(key string) []*editor.Host
+	if c.SidebarMode == ListByAddress {
+		keys, hostsFor = c.HostFile.Addresses(), c.HostFile.HostsForAddress
+	} else {
+		keys, hostsFor = c.HostFile.Domains(), c.HostFile.HostsForDomain
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return natural.Less(keys[i], keys[j])
+	})
+
+	// hosts without any address or domains, such as new entries, are listed
+	// first with an empty key
+	for _, host := range c.HostFile.Filter(func(host *editor.Host) bool {
+		if host.IsOnlyComment() {
+			return false
+		} else if c.SidebarMode == ListByAddress {
+			return host.Address() == ""
+		}
+		return strings.TrimSpace(strings.Join(host.Domains(), "")) == ""
+	}) {
+		rows = append(rows, SidebarRow{Host: host})
+	}
+
+	for _, key := range keys {
+		for idx, host := range hostsFor(key) {
+			row := SidebarRow{Key: key, Host: host}
+			if idx > 0 {
+				row.Key += " (" + strconv.Itoa(idx+1) + ")"
+			}
+			rows = append(rows, row)
+		}
+	}
+	return
+}
+
 func (c *CUI) updateEditorByAddressOrDomain() {
-	var choices map[string]*editor.Host
+	var comments []SidebarRow
+
+	onlyComments := c.HostFile.Filter(func(host *editor.Host) bool {
+		return host.IsOnlyComment()
+	})
+	for idx, host := range onlyComments {
+		if !c.sidebarFilterMatch(strings.Split(host.Comment(), "\n")...) {
+			continue
+		}
+		comments = append(comments, SidebarRow{Key: fmt.Sprintf("Comment (%d)", idx+1), Host: host})
+	}
+
+	sections := make(map[*SidebarList][]SidebarRow)
+	for _, row := range c.sidebarChoices() {
+		host := row.Host
+		if c.SidebarMode == ListByDomain {
+			// only the domain listed, not the other domains of the host
+			domain, _, _ := strings.Cut(row.Key, " (")
+			if !c.sidebarFilterMatch(domain, host.Address(), host.Lookup(), host.TrailingComment(), host.Comment()) {
+				continue
+			}
+		} else if !c.sidebarFilterHost(host) {
+			continue
+		}
+		section := c.sidebarSection(host)
+		sections[section] = append(sections[section], row)
+	}
+
+	c.SidebarCommentsList.SetRows(comments)
+	c.SidebarLocalsList.SetRows(sections[c.SidebarLocalsList])
+	c.SidebarCustomList.SetRows(sections[c.SidebarCustomList])
+	c.SidebarBlockedList.SetRows(sections[c.SidebarBlockedList])
+}
+
+// sidebarSection returns the list the host is listed in when listing by
+// domain or address
+func (c *CUI) sidebarSection(host *editor.Host) *SidebarList {
+	switch {
+	case host.Importance() != editor.HostNotImportant:
+		return c.SidebarLocalsList
+	case host.IsBlocked():
+		return c.SidebarBlockedList
+	}
+	return c.SidebarCustomList
+}
+
+// sidebarKeys returns the domains or the address of the host, depending on
+// how the hosts are listed
+func (c *CUI) sidebarKeys(host *editor.Host) (keys []string) {
 	if c.SidebarMode == ListByAddress {
-		choices = c.EditorAddressLookup
+		if address := host.Address(); address != "" {
+			keys = append(keys, address)
+		}
+		return
+	}
+	for _, domain := range host.Domains() {
+		if domain != "" {
+			keys = append(keys, domain)
+		}
+	}
+	return
+}
+
+// updateHostRows is refreshEditor for edits of the domains or address of the
+// host, which had the sidebar keys given before the edit. Only the rows for
+// those keys are listed again, instead of sorting all of them
+func (c *CUI) updateHostRows(host *editor.Host, previous []string) {
+	if c.SidebarFilter != "" {
+		// the edit may change which hosts match the filter
+		c.reloadEditor()
+		return
+	}
+	if host.Changed() {
+		c.SaveButton.SetSensitive(true)
+		c.ReloadButton.SetSensitive(true)
 	} else {
-		choices = c.EditorDomainsLookup
+		c.updateChangedButtons()
 	}
+	c.requestValidate()
+
+	if c.SidebarMode == ListByEntry {
+		rows := c.SidebarEntryList.rows
+		for idx, row := range rows {
+			if row.Host == host {
+				number, _, _ := strings.Cut(row.Key, ". ")
+				rows[idx].Key = number + ". " + host.Address()
+				break
+			}
+		}
+		c.SidebarEntryList.SetRows(rows)
+	} else {
+		c.updateKeyRows(host, append(previous, c.sidebarKeys(host)...))
+	}
+	c.Display.RequestDraw()
+	c.Display.RequestShow()
+}
 
-	var localsCount, customCount int
+// updateKeyRows lists the rows for the keys again, in each of the sections
+func (c *CUI) updateKeyRows(host *editor.Host, keys []string) {
+	hostsFor := c.HostFile.HostsForDomain
+	same := strings.EqualFold
+	if c.SidebarMode == ListByAddress {
+		hostsFor = c.HostFile.HostsForAddress
+		same = func(a, b string) bool { return a == b }
+	}
+	affected := func(key string) bool {
+		for _, k := range keys {
+			if same(k, key) {
+				return true
+			}
+		}
+		return false
+	}
 
-	for idx, host := range c.EditorCommentList {
-		key := fmt.Sprintf("Comment (%d)", idx+1)
-		b := c.makeSidebarButton(key, host)
-		c.SidebarCommentsList.PackStart(b, false, false, 0)
+	sections := make(map[*SidebarList][]SidebarRow)
+	for _, l := range []*SidebarList{c.SidebarLocalsList, c.SidebarCustomList, c.SidebarBlockedList} {
+		sections[l] = []SidebarRow{}
+		for _, row := range l.rows {
+			key, _, _ := strings.Cut(row.Key, " (")
+			if (key == "" && row.Host != host) || (key != "" && !affected(key)) {
+				sections[l] = append(sections[l], row)
+			}
+		}
+	}
+
+	insert := func(section *SidebarList, key string, rows ...SidebarRow) {
+		listed := sections[section]
+		at := sort.Search(len(listed), func(i int) bool {
+			other, _, _ := strings.Cut(listed[i].Key, " (")
+			return other != "" && (key == "" || natural.Less(key, other))
+		})
+		sections[section] = append(listed[:at], append(rows, listed[at:]...)...)
 	}
 
-	for _, key := range maps.SortedKeys(choices) {
-		host := choices[key]
-		b := c.makeSidebarButton(key, host)
-		switch host.Importance() {
-		case editor.HostIsLocalhostIPv4, editor.HostIsLocalhostIPv6:
-			localsCount += 1
-			c.SidebarLocalsList.PackStart(b, false, false, 0)
-			c.SidebarLocalsList.SetSizeRequest(gSidebarInnerWidth, localsCount)
-		default:
-			customCount += 1
-			c.SidebarCustomList.PackStart(b, false, false, 0)
-			c.SidebarCustomList.SetSizeRequest(gSidebarInnerWidth, customCount)
+	if len(c.sidebarKeys(host)) == 0 {
+		// listed first with an empty key, see sidebarChoices
+		insert(c.sidebarSection(host), "", SidebarRow{Host: host})
+	}
+	done := make(map[string]bool)
+	for _, key := range keys {
+		hosts := hostsFor(key)
+		if len(hosts) == 0 || done[strings.ToLower(key)] {
+			continue
 		}
+		done[strings.ToLower(key)] = true
+		// the key as first written, see editor.Hostfile.Domains
+		for _, listed := range c.sidebarKeys(hosts[0]) {
+			if same(listed, key) {
+				key = listed
+				break
+			}
+		}
+		rows := make(map[*SidebarList][]SidebarRow)
+		for idx, other := range hosts {
+			row := SidebarRow{Key: key, Host: other}
+			if idx > 0 {
+				row.Key += " (" + strconv.Itoa(idx+1) + ")"
+			}
+			section := c.sidebarSection(other)
+			rows[section] = append(rows[section], row)
+		}
+		for section, added := range rows {
+			insert(section, key, added...)
+		}
+	}
+
+	for section, rows := range sections {
+		section.SetRows(rows)
 	}
 }
 
 func (c *CUI) updateEditorByEntry() {
 	hosts := c.HostFile.Hosts()
-	c.SidebarEntryList.SetSizeRequest(-1, len(hosts))
 	var commentsCount int
+	var rows []SidebarRow
 	for idx, host := range hosts {
 		key := strconv.Itoa(idx+1) + ". "
 		if host.IsOnlyComment() {
@@ -566,65 +749,12 @@ func (c *CUI) updateEditorByEntry() {
 		} else {
 			key += host.Address()
 		}
-		b := c.makeSidebarButton(key, host)
-		c.SidebarEntryList.PackStart(b, false, false, 0)
-	}
-}
-
-func (c *CUI) makeSidebarButton(key string, host *editor.Host) (b ctk.Button) {
-	label := ctk.NewLabel(key)
-	label.Show()
-	label.SetJustify(cenums.JUSTIFY_LEFT)
-	label.SetSizeRequest(-1, 1)
-	label.SetSingleLineMode(true)
-
-	b = ctk.NewButtonWithWidget(label)
-	_ = b.InstallProperty(cdk.Property("host"), cdk.StructProperty, true, host)
-	b.Show()
-	b.SetSizeRequest(gSidebarInnerWidth, 1)
-	b.Connect(ctk.SignalActivate, key+"-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
-		if h, ok := data[0].(*editor.Host); ok {
-			c.focusEditor(h)
-		}
-		return cenums.EVENT_STOP
-	}, host)
-
-	var theme paint.Theme
-	var name, tooltip string
-
-	if c.SelectedHost != nil && c.SelectedHost.Equals(host) {
-		name = "editing-list-selected"
-	} else {
-		name = "editing-list-unselected"
-	}
-
-	if host.Active() {
-		theme = SidebarActiveTheme
-		tooltip = key + " is active"
-	} else {
-		theme = SidebarButtonTheme
-		tooltip = key + " is inactive"
-	}
-
-	switch host.Importance() {
-	case editor.HostIsLocalhostIPv4:
-		tooltip += "\n" + key + " points to an IPv4 localhost address"
-	case editor.HostIsLocalhostIPv6:
-		tooltip += "\n" + key + " points to an IPv6 localhost address"
-	default:
-		if lookup := host.Lookup(); lookup == "" {
-			tooltip += "\n" + key + " points to a static ip address"
-		} else {
-			tooltip += "\n" + key + " points to a dynamic ip address"
-			tooltip += "\n" + key + " gets the ip from " + lookup
+		if !c.sidebarFilterHost(host) {
+			continue
 		}
+		rows = append(rows, SidebarRow{Key: key, Host: host})
 	}
-
-	b.SetName(name)
-	b.SetTheme(theme)
-	b.SetHasTooltip(true)
-	b.SetTooltipText(tooltip)
-	return
+	c.SidebarEntryList.SetRows(rows)
 }
 
 func (c *CUI) focusEditor(host *editor.Host) {
@@ -636,11 +766,19 @@ func (c *CUI) focusEditor(host *editor.Host) {
 		c.Window.ReApplyStyles()
 		c.Display.RequestDraw()
 		c.Display.RequestShow()
-		c.reloadEditor()
+		c.renderSidebarLists()
 	}()
 
+	if len(c.Marked) > 0 {
+		c.SelectedHost = host
+		c.showMarkedPanel()
+		return
+	}
+	c.MarkedSelectedFrame.Hide()
+
 	if host == nil {
 		c.Window.LogDebug("clearing editor focus")
+		c.cancelLookup()
 		c.SelectedHost = nil
 		c.HostSelectedFrame.Hide()
 		c.NothingSelectedFrame.Show()
@@ -662,9 +800,9 @@ func (c *CUI) focusEditor(host *editor.Host) {
 	_ = c.CommentsEntry.Disconnect(ctk.SignalChangedText, "comments-changed-handler")
 	c.CommentsEntry.Connect(ctk.SignalChangedText, "comments-changed-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
 		h, _ := data[0].(*editor.Host)
-		h.SetComment(c.CommentsEntry.GetText())
+		c.editHost(h, "comment", func() { h.SetComment(c.CommentsEntry.GetText()) })
 		c.CommentsEntry.LogDebug("updated host %v comment: %v", h.Address(), c.CommentsEntry.GetText())
-		c.reloadEditor()
+		c.refreshEditor()
 		return cenums.EVENT_STOP
 	}, host)
 
@@ -692,14 +830,25 @@ func (c *CUI) focusEditor(host *editor.Host) {
 	c.DomainsEntry.SetText(strings.Join(domainLines, "\n"))
 	c.DomainsEntry.Connect(ctk.SignalChangedText, "domains-changed-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
 		h, _ := data[0].(*editor.Host)
-		h.SetDomains(c.DomainsEntry.GetText())
-		c.reloadEditor()
+		previous := c.sidebarKeys(h)
+		c.editHost(h, "domains", func() { h.SetDomains(c.DomainsEntry.GetText()) })
+		c.updateHostRows(h, previous)
+		return cenums.EVENT_STOP
+	}, host)
+
+	_ = c.TrailingEntry.Disconnect(ctk.SignalChangedText, "trailing-changed-handler")
+	c.TrailingEntry.SetText(host.TrailingComment())
+	c.TrailingEntry.Connect(ctk.SignalChangedText, "trailing-changed-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		h, _ := data[0].(*editor.Host)
+		c.editHost(h, "trailing", func() { h.SetTrailingComment(c.TrailingEntry.GetText()) })
+		c.TrailingEntry.LogDebug("updated host %v trailing comment: %v", h.Address(), c.TrailingEntry.GetText())
+		c.refreshEditor()
 		return cenums.EVENT_STOP
 	}, host)
 
 	handle := "activate-button-handler"
 	_ = c.ActivateButton.Disconnect(ctk.SignalActivate, handle)
-	if host.Importance() != editor.HostNotImportant {
+	if host.IsProtected() {
 
 		c.AddressEntry.SetSensitive(false)
 		c.AddressButton.SetSensitive(false)
@@ -739,14 +888,9 @@ func (c *CUI) focusEditor(host *editor.Host) {
 			d.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
 				switch response {
 				case enums.ResponseYes:
-					for idx, hh := range c.HostFile.Hosts() {
-						if h.Equals(hh) {
-							log.DebugF("removing entry at index: %v", idx)
-							c.HostFile.RemoveHost(idx)
-							c.SelectedHost = nil
-							break
-						}
-					}
+					log.DebugF("removing entry: %v", h.ID())
+					c.removeHost(h)
+					c.SelectedHost = nil
 					c.reloadEditor()
 					c.focusEditor(nil)
 				case enums.ResponseCancel, enums.ResponseClose, enums.ResponseNo:
@@ -762,7 +906,7 @@ func (c *CUI) focusEditor(host *editor.Host) {
 			c.ActivateButton.SetLabel("click to deactivate")
 			c.ActivateButton.Connect(ctk.SignalActivate, handle, func(data []interface{}, argv ...interface{}) cenums.EventFlag {
 				if h, ok := data[0].(*editor.Host); ok {
-					h.SetActive(false)
+					c.editHost(h, "", func() { h.SetActive(false) })
 					c.reloadEditor()
 					c.focusEditor(h)
 				}
@@ -775,7 +919,7 @@ func (c *CUI) focusEditor(host *editor.Host) {
 			c.ActivateButton.SetLabel("click to activate")
 			c.ActivateButton.Connect(ctk.SignalActivate, handle, func(data []interface{}, argv ...interface{}) cenums.EventFlag {
 				if h, ok := data[0].(*editor.Host); ok {
-					h.SetActive(true)
+					c.editHost(h, "", func() { h.SetActive(true) })
 					c.reloadEditor()
 					c.focusEditor(h)
 				}
@@ -789,18 +933,13 @@ func (c *CUI) focusEditor(host *editor.Host) {
 	c.AddressButton.Connect(
 		ctk.SignalActivate,
 		"address-activate-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
-			if err := c.newNsLookupDialog(host); err != nil {
-				c.AddressButton.LogErr(err)
-			}
+			c.requestNsLookup(host)
 			return cenums.EVENT_STOP
 		},
 		host,
 	)
 
-	actualLabel, actualTooltip := host.GetActualInfo()
-	c.AddressButton.SetLabel(actualLabel)
-	c.AddressButton.SetTooltipText(actualLabel + "\n" + actualTooltip)
-	c.AddressButton.Resize()
+	c.resolveAddressButton(host)
 
 	_ = c.AddressEntry.Disconnect(ctk.SignalChangedText, "address-text-changed-handler")
 	c.AddressEntry.Connect(ctk.SignalChangedText, "address-text-changed-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
@@ -808,78 +947,41 @@ func (c *CUI) focusEditor(host *editor.Host) {
 		h, _ = data[0].(*editor.Host)
 		text := h.Address()
 		changed := c.AddressEntry.GetText()
-		if cstrings.StringIsIP(changed) {
-			if text != changed {
-				c.AddressButton.SetLabel(fmt.Sprintf("(%v)", changed))
-				c.AddressButton.SetTooltipText("is a valid IP address")
+		previous := c.sidebarKeys(h)
+		c.cancelLookup()
+		c.editHost(h, "address", func() {
+			if cstrings.StringIsIP(changed) {
+				if text != changed {
+					c.AddressButton.SetLabel(fmt.Sprintf("(%v)", changed))
+					c.AddressButton.SetTooltipText("is a valid IP address")
+				} else {
+					c.AddressButton.SetLabel(fmt.Sprintf("(%v)", text))
+					c.AddressButton.SetTooltipText("is a valid IP address")
+				}
+				c.AddressButton.SetSensitive(false)
+				h.SetAddress(changed)
+			} else if cstrings.StringIsDomainName(changed) {
+				if text != changed {
+					c.AddressButton.SetLabel("(lookup changed)")
+					c.AddressButton.SetTooltipText("click to perform domain lookup")
+				}
+				c.AddressButton.SetSensitive(true)
+				h.SetAddress(changed)
+				h.SetLookup(changed)
 			} else {
-				c.AddressButton.SetLabel(fmt.Sprintf("(%v)", text))
-				c.AddressButton.SetTooltipText("is a valid IP address")
+				c.AddressButton.SetSensitive(false)
+				c.AddressButton.SetLabel("(not ip or domain)")
+				c.AddressButton.SetTooltipText("enter a valid address or domain name")
+				h.SetAddress(changed)
+				h.SetLookup("")
 			}
-			c.AddressButton.SetSensitive(false)
-			h.SetAddress(changed)
-		} else if cstrings.StringIsDomainName(changed) {
-			if text != changed {
-				c.AddressButton.SetLabel("(lookup changed)")
-				c.AddressButton.SetTooltipText("click to perform domain lookup")
-			}
-			c.AddressButton.SetSensitive(true)
-			h.SetAddress(changed)
-			h.SetLookup(changed)
-		} else {
-			c.AddressButton.SetSensitive(false)
-			c.AddressButton.SetLabel("(not ip or domain)")
-			c.AddressButton.SetTooltipText("enter a valid address or domain name")
-			h.SetAddress(changed)
-			h.SetLookup("")
-		}
-		c.reloadEditor()
+		})
+		c.updateHostRows(h, previous)
 		return cenums.EVENT_PASS
 	}, host)
 
-	allEntries := append(
-		append(
-			c.SidebarLocalsList.GetChildren(),
-			c.SidebarCustomList.GetChildren()...,
-		),
-		c.SidebarEntryList.GetChildren()...,
-	)
-	for _, child := range allEntries {
-		if b, ok := child.(ctk.Button); ok {
-			var h *editor.Host
-			if v, err := b.GetStructProperty(cdk.Property("host")); err != nil {
-				b.LogError("error getting button host: %v", err)
-				continue
-			} else if h, ok = v.(*editor.Host); !ok {
-				b.LogError("error button host is not an *editor.Host")
-				continue
-			}
-			switch c.SidebarMode {
-			case ListByEntry:
-				if c.SelectedHost != nil && c.SelectedHost.IsOnlyComment() {
-					if h.Equals(c.SelectedHost) {
-						b.SetName("editing-list-selected")
-					} else {
-						b.SetName("editing-list-unselected")
-					}
-					break
-				}
-				fallthrough
-			case ListByAddress:
-				if h.Address() == host.Address() {
-					b.SetName("editing-list-selected")
-				} else {
-					b.SetName("editing-list-unselected")
-				}
-			case ListByDomain:
-				_, key, _ := getSidebarButtonInfo(b)
-				if host.Address() == h.Address() && cstrings.StringSliceHasValue(host.Domains(), key) {
-					b.SetName("editing-list-selected")
-				} else {
-					b.SetName("editing-list-unselected")
-				}
-			}
-		}
+	for _, l := range c.sidebarLists() {
+		l.ScrollToHost(host)
 	}
 
 	if host.IsOnlyComment() {
@@ -892,27 +994,3 @@ func (c *CUI) focusEditor(host *editor.Host) {
 		c.ActivateButton.Show()
 	}
 }
-
-var rxSidebarButtonLabel = regexp.MustCompile(`^(\d+\.)??\s??(\S+?)\s??(\(\d+\))??$`)
-
-func getSidebarButtonInfo(b ctk.Button) (idx int, key, extra string) {
-	key = b.GetLabel()
-	if rxSidebarButtonLabel.MatchString(key) {
-		m := rxSidebarButtonLabel.FindAllStringSubmatch(key, 1)
-		var err error
-		if idx, err = strconv.Atoi(m[0][1]); err == nil {
-			idx -= 1
-		}
-		key = m[0][2]
-		extra = m[0][3]
-		return
-	}
-	// fallback?
-	parts := strings.Split(key, " ")
-	if len(parts) <= 1 {
-		return
-	}
-	key = parts[0]
-	extra = strings.Join(parts[1:], " ")
-	return
-}
@@ -0,0 +1,131 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"path"
+	"strings"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// sidebarFilterMatch reports whether any of the values match the sidebar
+// filter, which is a case-insensitive glob pattern when it has any of the
+// "*?[" characters and a case-insensitive substring otherwise
+func (c *CUI) sidebarFilterMatch(values ...string) bool {
+	filter := strings.ToLower(strings.TrimSpace(c.SidebarFilter))
+	if filter == "" {
+		return true
+	}
+	glob := strings.ContainsAny(filter, "*?[")
+	for _, value := range values {
+		value = strings.ToLower(value)
+		if glob {
+			if matched, _ := path.Match(filter, value); matched {
+				return true
+			}
+		} else if strings.Contains(value, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// sidebarFilterHost matches the sidebar filter against all the domains,
+// address and comments of the host
+func (c *CUI) sidebarFilterHost(host *editor.Host) bool {
+	values := []string{host.Address(), host.Lookup(), host.TrailingComment()}
+	values = append(values, host.Domains()...)
+	values = append(values, strings.Split(host.Comment(), "\n")...)
+	return c.sidebarFilterMatch(values...)
+}
+
+func (c *CUI) setSidebarFilter(filter string) {
+	if c.SidebarFilter == filter {
+		return
+	}
+	c.SidebarFilter = filter
+	c.reloadEditor()
+}
+
+// editingText reports whether any of the text entries has the focus
+func (c *CUI) editingText() bool {
+	for _, entry := range []interface{ HasFocus() bool }{
+		c.SidebarFilterEntry,
+		c.CommentsEntry,
+		c.AddressEntry,
+		c.DomainsEntry,
+		c.TrailingEntry,
+		c.MarkedAddressEntry,
+	} {
+		if entry.HasFocus() {
+			return true
+		}
+	}
+	return false
+}
+
+// windowKeyHandler focuses the sidebar filter on "/", unless editing text,
+// clears the sidebar filter on Escape and moves through the sidebar lists
+// with the cursor keys
+func (c *CUI) windowKeyHandler(_ []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) < 2 {
+		return cenums.EVENT_PASS
+	}
+	e, ok := argv[1].(*cdk.EventKey)
+	if !ok {
+		return cenums.EVENT_PASS
+	}
+	switch {
+	case e.Key() == cdk.KeyRune && e.Rune() == '/' && !c.editingText():
+		c.SidebarFilterEntry.GrabFocus()
+		return cenums.EVENT_STOP
+	case e.Key() == cdk.KeyEsc && c.SidebarFilterEntry.HasFocus():
+		c.SidebarFilterEntry.SetText("")
+		c.setSidebarFilter("")
+		return cenums.EVENT_STOP
+	}
+	for _, l := range c.sidebarLists() {
+		if f := l.processKey(e); f == cenums.EVENT_STOP {
+			c.Display.RequestDraw()
+			c.Display.RequestShow()
+			return f
+		}
+	}
+	return cenums.EVENT_PASS
+}
+
+// windowMouseHandler scrolls the sidebar list under the mouse pointer with
+// the mouse wheel and marks ranges of rows with shift-click
+func (c *CUI) windowMouseHandler(_ []interface{}, argv ...interface{}) cenums.EventFlag {
+	if len(argv) < 2 {
+		return cenums.EVENT_PASS
+	}
+	e, ok := argv[1].(*cdk.EventMouse)
+	if !ok {
+		return cenums.EVENT_PASS
+	}
+	for _, l := range c.sidebarLists() {
+		if f := l.processMouse(e); f == cenums.EVENT_STOP {
+			c.Display.RequestDraw()
+			c.Display.RequestShow()
+			return f
+		}
+	}
+	return cenums.EVENT_PASS
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"time"
+
+	"github.com/go-curses/cdk/log"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// gHistoryCoalesceDelay is how long after a text change another change to
+// the same field of the same host is merged into the same undo step
+const gHistoryCoalesceDelay = 2 * time.Second
+
+// EditHistory is the undo and redo stacks of editor operations
+type EditHistory struct {
+	undo []*historyStep
+	redo []*historyStep
+}
+
+// historyStep is either a change to the content of a host, restored with
+// before and after, or a change to the order of hosts, restored with the
+// undo and redo functions
+type historyStep struct {
+	host  *editor.Host
+	field string
+	when  time.Time
+
+	before editor.Entry
+	after  editor.Entry
+
+	undo func()
+	redo func()
+}
+
+func (s *historyStep) revert() {
+	if s.undo != nil {
+		s.undo()
+	} else {
+		s.host.SetEntry(s.before)
+	}
+}
+
+func (s *historyStep) apply() {
+	if s.redo != nil {
+		s.redo()
+	} else {
+		s.host.SetEntry(s.after)
+	}
+}
+
+// CanUndo reports whether there are any steps to undo
+func (h *EditHistory) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+// CanRedo reports whether there are any undone steps to redo
+func (h *EditHistory) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// Clear forgets all steps
+func (h *EditHistory) Clear() {
+	h.undo, h.redo = nil, nil
+}
+
+func (h *EditHistory) push(step *historyStep) {
+	h.undo = append(h.undo, step)
+	h.redo = nil
+}
+
+// coalesce merges a text change into the last step when it is a recent
+// change to the same field of the same host
+func (h *EditHistory) coalesce(host *editor.Host, field string, after editor.Entry) bool {
+	if field == "" || len(h.undo) == 0 {
+		return false
+	}
+	last := h.undo[len(h.undo)-1]
+	if last.undo != nil || last.host != host || last.field != field || time.Since(last.when) > gHistoryCoalesceDelay {
+		return false
+	}
+	last.after, last.when = after, time.Now()
+	h.redo = nil
+	return true
+}
+
+// editHost records the changes made to the host by edit. Consecutive changes
+// to the same field, such as typing in a text entry, are undone together
+func (c *CUI) editHost(host *editor.Host, field string, edit func()) {
+	before := host.Entry()
+	edit()
+	after := host.Entry()
+	if c.History.coalesce(host, field, after) {
+		return
+	}
+	c.History.push(&historyStep{
+		host:   host,
+		field:  field,
+		when:   time.Now(),
+		before: before,
+		after:  after,
+	})
+}
+
+func (c *CUI) insertHost(host *editor.Host, idx int) {
+	c.HostFile.InsertHost(host, idx)
+	c.History.push(&historyStep{
+		host: host,
+		when: time.Now(),
+		undo: func() { c.HostFile.RemoveHost(host) },
+		redo: func() { c.HostFile.InsertHost(host, idx) },
+	})
+}
+
+// insertHosts inserts all the hosts, in order, at idx as a single step
+func (c *CUI) insertHosts(hosts []*editor.Host, idx int) {
+	positions := make([]int, len(hosts))
+	for jdx := range hosts {
+		positions[jdx] = idx + jdx
+	}
+	c.HostFile.InsertHosts(hosts, positions)
+	c.History.push(&historyStep{
+		host: hosts[0],
+		when: time.Now(),
+		undo: func() { c.HostFile.RemoveHosts(hosts...) },
+		redo: func() { c.HostFile.InsertHosts(hosts, positions) },
+	})
+}
+
+func (c *CUI) removeHost(host *editor.Host) {
+	idx := c.HostFile.RemoveHost(host)
+	if idx < 0 {
+		return
+	}
+	c.History.push(&historyStep{
+		host: host,
+		when: time.Now(),
+		undo: func() { c.HostFile.InsertHost(host, idx) },
+		redo: func() { c.HostFile.RemoveHost(host) },
+	})
+}
+
+func (c *CUI) moveHost(host *editor.Host, to int) {
+	from := c.HostFile.MoveHost(host, to)
+	if from < 0 {
+		return
+	}
+	c.History.push(&historyStep{
+		host: host,
+		when: time.Now(),
+		undo: func() { c.HostFile.MoveHost(host, from) },
+		redo: func() { c.HostFile.MoveHost(host, to) },
+	})
+}
+
+func (c *CUI) requestUndo() {
+	if !c.History.CanUndo() {
+		log.DebugF("nothing to undo")
+		return
+	}
+	last := len(c.History.undo) - 1
+	step := c.History.undo[last]
+	c.History.undo = c.History.undo[:last]
+	step.revert()
+	c.History.redo = append(c.History.redo, step)
+	c.focusHistoryStep(step)
+}
+
+func (c *CUI) requestRedo() {
+	if !c.History.CanRedo() {
+		log.DebugF("nothing to redo")
+		return
+	}
+	last := len(c.History.redo) - 1
+	step := c.History.redo[last]
+	c.History.redo = c.History.redo[:last]
+	step.apply()
+	c.History.undo = append(c.History.undo, step)
+	c.focusHistoryStep(step)
+}
+
+// focusHistoryStep focuses the editor on the host of the step, unless it is
+// no longer present
+func (c *CUI) focusHistoryStep(step *historyStep) {
+	var focus *editor.Host
+	for _, host := range c.HostFile.Hosts() {
+		if host == step.host {
+			focus = host
+			break
+		}
+	}
+	c.reloadEditor()
+	c.focusEditor(focus)
+}
@@ -17,7 +17,9 @@ package ui
 import (
 	"fmt"
 
+	"github.com/go-curses/cdk"
 	"github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/cdk/lib/paint"
 	"github.com/go-curses/cdk/lib/paths"
 	"github.com/go-curses/cdk/lib/ptypes"
 	"github.com/go-curses/cdk/log"
@@ -35,7 +37,7 @@ func (c *CUI) startup(_ []interface{}, argv ...interface{}) enums.EventFlag {
 			c.SourceFile = c.Display.App().GetContext().Args().First()
 		}
 		if c.SourceFile == "" {
-			c.SourceFile = "/etc/hosts"
+			c.SourceFile = editor.DefaultHostsFile
 		}
 
 		if !paths.IsFile(c.SourceFile) {
@@ -65,11 +67,44 @@ func (c *CUI) startup(_ []interface{}, argv ...interface{}) enums.EventFlag {
 			title += " [read-only]"
 		}
 
-		if c.HostFile, err = editor.ParseFile(c.SourceFile); err != nil {
+		if c.HostFile, c.ParseReport, err = editor.ParseFileWithReport(c.SourceFile); err != nil {
 			c.LastError = fmt.Errorf("error parsing %v: %v", c.SourceFile, err)
 			log.Error(c.LastError)
 			return enums.EVENT_STOP
 		}
+		c.Backups = c.Display.App().GetContext().Int("backups")
+		editor.DefaultResolver = editor.NewResolver(
+			c.Display.App().GetContext().String("dns-server"),
+			c.Display.App().GetContext().Duration("dns-timeout"),
+			editor.DefaultLookupTTL,
+		)
+		c.HostFile.Backups = c.Backups
+
+		presetsDir := c.Display.App().GetContext().String("presets")
+		if presetsDir == "" {
+			presetsDir = editor.DefaultPresetsDir()
+		}
+		// the presets which loaded are used even when others did not
+		presets, presetsErr := editor.LoadPresets(presetsDir)
+		if presetsErr != nil {
+			log.WarnF("error loading presets from %v: %v", presetsDir, presetsErr)
+		}
+		c.Presets = append(editor.BuiltinPresets(), presets...)
+
+		if name := c.Display.App().GetContext().String("sidebar"); name != "" {
+			if mode, ok := ParseSidebarListMode(name); ok {
+				c.SidebarMode = mode
+			} else {
+				log.WarnF("unknown sidebar listing %q, listing by domain", name)
+			}
+		}
+		if theme := c.Display.App().GetContext().String("theme"); theme != "" {
+			if color, ok := paint.ParseColor(theme); ok {
+				initThemes(color)
+			} else {
+				log.WarnF("unknown theme color %q", theme)
+			}
+		}
 
 		ctk.GetAccelMap().LoadFromString(eheditorAccelMap)
 
@@ -83,6 +118,8 @@ func (c *CUI) startup(_ []interface{}, argv ...interface{}) enums.EventFlag {
 		}
 
 		c.Window.AddAccelGroup(c.makeAccelmap())
+		c.Window.Connect(ctk.SignalEventKey, "eheditor-key-handler", c.windowKeyHandler)
+		c.Window.Connect(ctk.SignalEventMouse, "eheditor-mouse-handler", c.windowMouseHandler)
 
 		vbox := c.Window.GetVBox()
 		vbox.SetSpacing(0)
@@ -100,12 +137,23 @@ func (c *CUI) startup(_ []interface{}, argv ...interface{}) enums.EventFlag {
 		c.App.NotifyStartupComplete()
 		c.Window.Show()
 
+		_ = c.Display.AsyncCall(func(_ cdk.Display) error {
+			if len(c.ParseReport) > 0 {
+				// the repair dialog follows the parse report
+				c.newParseReportDialog()
+			} else {
+				c.newRepairDialog()
+			}
+			return nil
+		})
+
 		return enums.EVENT_PASS
 	}
 	return enums.EVENT_STOP
 }
 
 func (c *CUI) shutdown(_ []interface{}, _ ...interface{}) enums.EventFlag {
+	c.cancelLookup()
 	if c.LastError != nil {
 		fmt.Printf("%v\n", c.LastError)
 		log.InfoF("exiting (with error)")
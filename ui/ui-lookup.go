@@ -0,0 +1,111 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-curses/cdk"
+	cstrings "github.com/go-curses/cdk/lib/strings"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+const gLookupPendingLabel = "resolving…"
+
+// startLookup cancels any lookup still running and returns the context for
+// the next one. Lookups are only started from the display's event loop, so
+// the cancel func needs no locking
+func (c *CUI) startLookup() (ctx context.Context) {
+	c.cancelLookup()
+	ctx, c.lookupCancel = context.WithCancel(context.Background())
+	return
+}
+
+// cancelLookup cancels any lookup still running, its results are discarded
+func (c *CUI) cancelLookup() {
+	if c.lookupCancel != nil {
+		c.lookupCancel()
+		c.lookupCancel = nil
+	}
+}
+
+// afterLookup runs fn on the display's event loop, unless the lookup was
+// cancelled in the meantime
+func (c *CUI) afterLookup(ctx context.Context, fn func()) {
+	if ctx.Err() != nil {
+		return
+	}
+	_ = c.Display.AsyncCall(func(d cdk.Display) error {
+		if ctx.Err() == nil {
+			fn()
+			d.RequestDraw()
+			d.RequestShow()
+		}
+		return nil
+	})
+}
+
+func (c *CUI) setAddressButtonInfo(label, tooltip string) {
+	c.AddressButton.SetLabel(label)
+	c.AddressButton.SetTooltipText(label + "\n" + tooltip)
+	c.AddressButton.Resize()
+}
+
+// resolveAddressButton shows the actual info of the host on the
+// AddressButton. Hosts with a lookup domain show a pending state until the
+// lookup completes in the background
+func (c *CUI) resolveAddressButton(host *editor.Host) {
+	ctx := c.startLookup()
+	if !cstrings.StringIsDomainName(host.Lookup()) {
+		c.setAddressButtonInfo(host.GetActualInfoWith(ctx, editor.DefaultResolver))
+		return
+	}
+	c.setAddressButtonInfo(
+		fmt.Sprintf("%v (%v)", host.Address(), gLookupPendingLabel),
+		"looking up "+host.Lookup(),
+	)
+	go func() {
+		label, tooltip := host.GetActualInfoWith(ctx, editor.DefaultResolver)
+		c.afterLookup(ctx, func() {
+			c.cancelLookup()
+			c.setAddressButtonInfo(label, tooltip)
+		})
+	}()
+}
+
+// requestNsLookup looks up the addresses of the host's lookup domain in the
+// background and then presents them for selection
+func (c *CUI) requestNsLookup(host *editor.Host) {
+	ctx := c.startLookup()
+	c.setAddressButtonInfo(
+		fmt.Sprintf("%v (%v)", host.Address(), gLookupPendingLabel),
+		"looking up "+host.Lookup(),
+	)
+	go func() {
+		found, err := host.LookupWith(ctx, editor.DefaultResolver)
+		label, tooltip := host.GetActualInfoWith(ctx, editor.DefaultResolver)
+		c.afterLookup(ctx, func() {
+			c.cancelLookup()
+			c.setAddressButtonInfo(label, tooltip)
+			if err != nil {
+				c.AddressButton.LogErr(err)
+			} else if err = c.newNsLookupDialog(host, found); err != nil {
+				c.AddressButton.LogErr(err)
+			}
+		})
+	}()
+}
@@ -0,0 +1,376 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cenums "github.com/go-curses/cdk/lib/enums"
+	cstrings "github.com/go-curses/cdk/lib/strings"
+	"github.com/go-curses/cdk/log"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// gSidebarMarkedBadge prefixes the sidebar buttons of marked hosts
+const gSidebarMarkedBadge = "+"
+
+// isMarked reports whether the host is marked for a bulk action
+func (c *CUI) isMarked(host *editor.Host) bool {
+	return c.Marked[host]
+}
+
+// toggleMark marks the host, or unmarks it when already marked, and makes
+// it the anchor of following range marks
+func (c *CUI) toggleMark(host *editor.Host) {
+	if c.Marked == nil {
+		c.Marked = make(map[*editor.Host]bool)
+	}
+	if c.Marked[host] {
+		delete(c.Marked, host)
+	} else {
+		c.Marked[host] = true
+	}
+	c.markAnchor = host
+	c.updateMarks()
+}
+
+// markRange marks the rows of the list from the anchor to the row at idx,
+// or only the row at idx when the anchor is not one of the rows
+func (c *CUI) markRange(l *SidebarList, idx int) {
+	if idx < 0 || idx >= len(l.rows) {
+		return
+	}
+	from := idx
+	for jdx, row := range l.rows {
+		if row.Host == c.markAnchor {
+			from = jdx
+			break
+		}
+	}
+	if c.Marked == nil {
+		c.Marked = make(map[*editor.Host]bool)
+	}
+	for jdx := min(from, idx); jdx <= max(from, idx); jdx++ {
+		c.Marked[l.rows[jdx].Host] = true
+	}
+	if c.markAnchor == nil || from == idx {
+		c.markAnchor = l.rows[idx].Host
+	}
+	c.updateMarks()
+}
+
+// clearMarks unmarks all hosts
+func (c *CUI) clearMarks() {
+	if len(c.Marked) == 0 {
+		return
+	}
+	c.Marked, c.markAnchor = nil, nil
+	c.updateMarks()
+}
+
+// updateMarks shows the marked hosts in the sidebar and the bulk actions in
+// place of the editor, or the editor again when nothing is marked
+func (c *CUI) updateMarks() {
+	c.focusEditor(c.SelectedHost)
+}
+
+// markedHosts returns the marked hosts which are still present, in the
+// hosts file order
+func (c *CUI) markedHosts() (hosts []*editor.Host) {
+	if len(c.Marked) == 0 {
+		return
+	}
+	for _, host := range c.HostFile.Hosts() {
+		if c.Marked[host] {
+			hosts = append(hosts, host)
+		}
+	}
+	return
+}
+
+// makeMarkedPanel makes the panel shown in place of the editor while hosts
+// are marked, with the actions applied to all of them at once
+func (c *CUI) makeMarkedPanel() {
+	c.MarkedSelectedFrame = ctk.NewFrame("")
+	c.MarkedSelectedFrame.SetLabelAlign(0.0, 0.5)
+	c.EditingHBox.PackStart(c.MarkedSelectedFrame, true, true, 0)
+
+	panelVBox := ctk.NewVBox(false, 0)
+	panelVBox.Show()
+	c.MarkedSelectedFrame.Add(panelVBox)
+
+	addInstructions := func(text string) {
+		sep := ctk.NewSeparator()
+		sep.Show()
+		sep.SetSizeRequest(-1, 1)
+		panelVBox.PackStart(sep, false, false, 0)
+		label := ctk.NewLabel(text)
+		label.Show()
+		label.SetSingleLineMode(true)
+		label.SetSizeRequest(-1, 1)
+		panelVBox.PackStart(label, false, false, 0)
+	}
+
+	newButtonBox := func() (hbox ctk.HBox) {
+		hbox = ctk.NewHBox(true, 1)
+		hbox.Show()
+		hbox.SetSizeRequest(-1, 1)
+		panelVBox.PackStart(hbox, false, false, 0)
+		return
+	}
+
+	addButton := func(hbox ctk.HBox, label, handle string, action func()) {
+		button := ctk.NewButtonWithLabel(label)
+		button.Show()
+		button.SetSizeRequest(-1, 1)
+		button.Connect(ctk.SignalActivate, handle, func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+			action()
+			return cenums.EVENT_STOP
+		})
+		hbox.PackStart(button, true, true, 0)
+	}
+
+	c.MarkedSummaryLabel = ctk.NewLabel("")
+	c.MarkedSummaryLabel.Show()
+	c.MarkedSummaryLabel.SetLineWrap(true)
+	c.MarkedSummaryLabel.SetLineWrapMode(cenums.WRAP_WORD)
+	c.MarkedSummaryLabel.SetSizeRequest(-1, 3)
+	panelVBox.PackStart(c.MarkedSummaryLabel, false, false, 0)
+
+	addInstructions("Marked entry actions:")
+	hbox := newButtonBox()
+	addButton(hbox, "activate all", "marked-activate-handler", func() { c.requestBulkActivate(true) })
+	addButton(hbox, "deactivate all", "marked-deactivate-handler", func() { c.requestBulkActivate(false) })
+	addButton(hbox, "delete all", "marked-delete-handler", c.requestBulkDelete)
+
+	addInstructions("Change the address of all marked entries to:")
+	c.MarkedAddressEntry = ctk.NewEntry("")
+	c.MarkedAddressEntry.Show()
+	c.MarkedAddressEntry.SetSelectable(true)
+	c.MarkedAddressEntry.SetLineWrap(false)
+	c.MarkedAddressEntry.SetSingleLineMode(true)
+	c.MarkedAddressEntry.SetSizeRequest(-1, 1)
+	panelVBox.PackStart(c.MarkedAddressEntry, false, false, 0)
+	addButton(newButtonBox(), "change address", "marked-address-handler", c.requestBulkSetAddress)
+
+	addInstructions("Move all marked entries in the hosts file order:")
+	hbox = newButtonBox()
+	addButton(hbox, "move to the top", "marked-move-top-handler", func() { c.requestBulkMove(true) })
+	addButton(hbox, "move to the bottom", "marked-move-bottom-handler", func() { c.requestBulkMove(false) })
+
+	filler := ctk.NewLabel("")
+	filler.Show()
+	panelVBox.PackStart(filler, true, true, 0)
+
+	addInstructions("Space marks, Shift extends and Escape clears the marks")
+	addButton(newButtonBox(), "clear marks", "marked-clear-handler", c.clearMarks)
+}
+
+// showMarkedPanel shows the bulk actions panel in place of the editor
+func (c *CUI) showMarkedPanel() {
+	marked := c.markedHosts()
+	c.MarkedSelectedFrame.SetLabel(fmt.Sprintf(" %d marked ", len(marked)))
+	var names []string
+	for _, host := range marked {
+		if len(names) == 5 {
+			names = append(names, fmt.Sprintf("and %d more", len(marked)-5))
+			break
+		}
+		names = append(names, describeMarkedHost(host))
+	}
+	c.MarkedSummaryLabel.SetText(strings.Join(names, ", "))
+	c.HostSelectedFrame.Hide()
+	c.NothingSelectedFrame.Hide()
+	c.MarkedSelectedFrame.Show()
+}
+
+func describeMarkedHost(host *editor.Host) string {
+	if host.IsOnlyComment() {
+		return "(comment)"
+	} else if domains := host.Domains(); len(domains) > 0 {
+		return domains[0]
+	}
+	return host.Name()
+}
+
+// bulkEditable reports whether a bulk action may change the host, which
+// excludes comments and the protected entries
+func bulkEditable(host *editor.Host) bool {
+	return !host.IsOnlyComment() && !host.IsProtected()
+}
+
+// confirmBulk asks whether to apply an action to the number of hosts given,
+// noting the number of marked hosts skipped, and calls fn if so
+func (c *CUI) confirmBulk(title, action string, count, skipped int, kind string, fn func()) {
+	if count == 0 {
+		ctk.NewMessageDialog(title, "None of the marked entries can be changed").RunFunc(func(_ enums.ResponseType, _ ...interface{}) {})
+		return
+	}
+	message := fmt.Sprintf("%v %d marked entries?", action, count)
+	if skipped > 0 {
+		message += fmt.Sprintf("\n(%d %v entries are skipped)", skipped, kind)
+	}
+	d := ctk.NewYesNoDialog(title, message, true)
+	d.SetSizeRequest(54, 8)
+	d.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
+		if response == enums.ResponseYes {
+			fn()
+			c.reloadEditor()
+			c.updateMarks()
+		} else {
+			log.DebugF("user cancelled %v", strings.ToLower(title))
+		}
+	})
+}
+
+// editHosts records the changes made to all the hosts by edit as a single
+// step, see editHost
+func (c *CUI) editHosts(hosts []*editor.Host, edit func(host *editor.Host)) {
+	before := make([]editor.Entry, len(hosts))
+	after := make([]editor.Entry, len(hosts))
+	for idx, host := range hosts {
+		before[idx] = host.Entry()
+		edit(host)
+		after[idx] = host.Entry()
+	}
+	c.History.push(&historyStep{
+		when: time.Now(),
+		undo: func() {
+			for idx, host := range hosts {
+				host.SetEntry(before[idx])
+			}
+		},
+		redo: func() {
+			for idx, host := range hosts {
+				host.SetEntry(after[idx])
+			}
+		},
+	})
+}
+
+func (c *CUI) requestBulkActivate(active bool) {
+	var hosts []*editor.Host
+	marked := c.markedHosts()
+	for _, host := range marked {
+		if bulkEditable(host) && host.Active() != active {
+			hosts = append(hosts, host)
+		}
+	}
+	title, action := "Activate Entries?", "Activate"
+	if !active {
+		title, action = "Deactivate Entries?", "Deactivate"
+	}
+	c.confirmBulk(title, action, len(hosts), len(marked)-len(hosts), "comment or protected", func() {
+		c.editHosts(hosts, func(host *editor.Host) { host.SetActive(active) })
+	})
+}
+
+func (c *CUI) requestBulkSetAddress() {
+	address := strings.TrimSpace(c.MarkedAddressEntry.GetText())
+	if !cstrings.StringIsIP(address) {
+		ctk.NewMessageDialog("Change Address", fmt.Sprintf("%q is not a valid IP address", address)).RunFunc(func(_ enums.ResponseType, _ ...interface{}) {})
+		return
+	}
+	var hosts []*editor.Host
+	marked := c.markedHosts()
+	for _, host := range marked {
+		if bulkEditable(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	c.confirmBulk("Change Address?", "Change the address to "+address+" for", len(hosts), len(marked)-len(hosts), "comment or protected", func() {
+		c.editHosts(hosts, func(host *editor.Host) {
+			host.SetLookup("")
+			host.SetAddress(address)
+		})
+	})
+}
+
+func (c *CUI) requestBulkDelete() {
+	var hosts []*editor.Host
+	marked := c.markedHosts()
+	for _, host := range marked {
+		if bulkEditable(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	c.confirmBulk("Remove Entries?", "Remove", len(hosts), len(marked)-len(hosts), "comment or protected", func() {
+		c.removeHosts(hosts)
+		c.Marked, c.markAnchor = nil, nil
+		if c.SelectedHost != nil && c.HostFile.IndexOf(c.SelectedHost) < 0 {
+			c.SelectedHost = nil
+		}
+	})
+}
+
+// requestBulkMove moves the marked hosts, in order, to the top or the bottom
+// of the hosts file, except for the comment entries
+func (c *CUI) requestBulkMove(top bool) {
+	var hosts []*editor.Host
+	marked := c.markedHosts()
+	for _, host := range marked {
+		if !host.IsOnlyComment() {
+			hosts = append(hosts, host)
+		}
+	}
+	title, action := "Move Entries?", "Move to the bottom"
+	if top {
+		action = "Move to the top"
+	}
+	c.confirmBulk(title, action, len(hosts), len(marked)-len(hosts), "comment", func() {
+		c.moveHosts(hosts, top)
+	})
+}
+
+// removeHosts removes all the hosts as a single step
+func (c *CUI) removeHosts(hosts []*editor.Host) {
+	positions := c.HostFile.RemoveHosts(hosts...)
+	c.History.push(&historyStep{
+		when: time.Now(),
+		undo: func() { c.HostFile.InsertHosts(hosts, positions) },
+		redo: func() { c.HostFile.RemoveHosts(hosts...) },
+	})
+}
+
+// moveHosts moves all the hosts, keeping their order, to the top or the
+// bottom of the hosts file as a single step
+func (c *CUI) moveHosts(hosts []*editor.Host, top bool) {
+	positions := c.HostFile.RemoveHosts(hosts...)
+	targets := make([]int, len(hosts))
+	start := 0
+	if !top {
+		start = c.HostFile.Len()
+	}
+	for idx := range hosts {
+		targets[idx] = start + idx
+	}
+	c.HostFile.InsertHosts(hosts, targets)
+	c.History.push(&historyStep{
+		when: time.Now(),
+		undo: func() {
+			c.HostFile.RemoveHosts(hosts...)
+			c.HostFile.InsertHosts(hosts, positions)
+		},
+		redo: func() {
+			c.HostFile.RemoveHosts(hosts...)
+			c.HostFile.InsertHosts(hosts, targets)
+		},
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-curses/cdk/log"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+func (c *CUI) newModifiedDialog() {
+	dialog := ctk.NewButtonMenuDialog(
+		"File Changed",
+		fmt.Sprintf("%v was changed by another program since it was read.", c.SourceFile),
+		"Merge Changes", 1,
+		"Overwrite", 2,
+	)
+	dialog.SetSizeRequest(48, 10)
+	dialog.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
+		switch response {
+		case 1:
+			c.requestMerge()
+		case 2:
+			if err := c.HostFile.Overwrite(); err != nil {
+				log.Error(err)
+			}
+			c.requestReload()
+		default:
+			log.DebugF("user cancelled save operation")
+		}
+	})
+}
+
+func (c *CUI) requestMerge() {
+	merge, err := c.HostFile.Merge()
+	if err != nil {
+		log.Error(err)
+		ctk.NewMessageDialog("Merge Failed", err.Error()).RunFunc(func(_ enums.ResponseType, _ ...interface{}) {})
+		return
+	}
+	c.resolveMergeConflicts(merge, 0)
+}
+
+func (c *CUI) resolveMergeConflicts(merge *editor.Merge, idx int) {
+	total := len(merge.Conflicts)
+	if idx >= total {
+		if err := merge.Apply(); err != nil {
+			log.Error(err)
+			return
+		}
+		if err := c.HostFile.Save(); err != nil {
+			log.Error(err)
+		}
+		c.requestReload()
+		return
+	}
+
+	conflict := merge.Conflicts[idx]
+	message := "Mine:\n" + describeMergeHost(conflict.Ours)
+	message += "\n\nTheirs:\n" + describeMergeHost(conflict.Theirs)
+
+	dialog := ctk.NewButtonMenuDialog(
+		fmt.Sprintf("Merge Conflict (%d of %d)", idx+1, total),
+		message,
+		"Keep Mine", 1,
+		"Keep Theirs", 2,
+	)
+	dialog.SetSizeRequest(54, 16)
+	dialog.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
+		switch response {
+		case 1:
+			conflict.Resolve(editor.MergeKeepOurs)
+		case 2:
+			conflict.Resolve(editor.MergeKeepTheirs)
+		default:
+			log.DebugF("user cancelled merge operation")
+			return
+		}
+		c.resolveMergeConflicts(merge, idx+1)
+	})
+}
+
+func describeMergeHost(host *editor.Host) string {
+	if host == nil {
+		return "(removed)"
+	}
+	if block := strings.TrimSpace(host.Block()); block != "" {
+		return block
+	}
+	return "(empty)"
+}
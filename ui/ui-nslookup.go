@@ -25,15 +25,9 @@ import (
 	editor "github.com/go-curses/coreutils-etc-hosts-editor"
 )
 
-func (c *CUI) newNsLookupDialog(host *editor.Host) (err error) {
+func (c *CUI) newNsLookupDialog(host *editor.Host, found []net.IP) (err error) {
 	c.EditingHBox.Freeze()
 
-	var found []net.IP
-	if found, err = host.PerformLookup(); err != nil {
-		c.EditingHBox.Thaw()
-		return err
-	}
-
 	numFound := len(found)
 	if numFound == 0 {
 		ctk.NewMessageDialog("nslookup", fmt.Sprintf("No hosts found for domain:\n%v", host.Lookup()))
@@ -62,7 +56,7 @@ func (c *CUI) newNsLookupDialog(host *editor.Host) (err error) {
 				if idx := int(response); idx > 0 {
 					ip := available[idx-1]
 					log.DebugF("selected ip: %v (idx=%v,found=%v)", ip.String(), idx, found)
-					h.SetAddress(ip.String())
+					c.editHost(h, "", func() { h.SetAddress(ip.String()) })
 					c.requestReloadContents()
 					c.focusEditor(h)
 				} else {
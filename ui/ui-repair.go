@@ -0,0 +1,69 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-curses/cdk/log"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+)
+
+// newRepairDialog offers to add the missing required entries, listing the
+// entries which would be added, unless there are none or in read-only mode
+func (c *CUI) newRepairDialog() {
+	hosts := c.HostFile.RepairHosts()
+	if len(hosts) == 0 || c.ReadOnlyMode {
+		return
+	}
+
+	message := fmt.Sprintf("%v is missing required entries, add these at the top?\n", c.SourceFile)
+	for _, host := range hosts {
+		message += fmt.Sprintf("\n%v %v", host.Address(), strings.Join(host.Domains(), " "))
+	}
+
+	dialog := ctk.NewYesNoDialog("Repair", message, false)
+	dialog.SetSizeRequest(64, strings.Count(message, "\n")+7)
+	dialog.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
+		if response == enums.ResponseYes {
+			c.requestRepair()
+		} else {
+			log.DebugF("repair declined")
+		}
+	})
+}
+
+// requestRepair adds the missing required entries as a single step
+func (c *CUI) requestRepair() {
+	added := c.HostFile.Repair()
+	if len(added) == 0 {
+		return
+	}
+	positions := make([]int, len(added))
+	for idx, host := range added {
+		positions[idx] = c.HostFile.IndexOf(host)
+	}
+	c.History.push(&historyStep{
+		host: added[0],
+		when: time.Now(),
+		undo: func() { c.HostFile.RemoveHosts(added...) },
+		redo: func() { c.HostFile.InsertHosts(added, positions) },
+	})
+	c.reloadEditor()
+	c.focusEditor(added[0])
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/go-curses/cdk/log"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
+)
+
+const gParseReportMaxLines = 10
+
+func (c *CUI) newParseReportDialog() {
+	total := len(c.ParseReport)
+	if total == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("%v has %d line(s) needing attention:\n", c.SourceFile, total)
+	for idx, diagnostic := range c.ParseReport {
+		if idx >= gParseReportMaxLines {
+			message += fmt.Sprintf("\n(and %d more)", total-idx)
+			break
+		}
+		message += fmt.Sprintf("\nline %d: %v: %v", diagnostic.Line, diagnostic.Severity, diagnostic.Reason)
+	}
+
+	dialog := ctk.NewMessageDialog("Parse Report", message)
+	_, h := dialog.GetSizeRequest()
+	dialog.SetSizeRequest(64, h)
+	dialog.RunFunc(func(response enums.ResponseType, argv ...interface{}) {
+		log.DebugF("parse report dismissed")
+		c.newRepairDialog()
+	})
+}
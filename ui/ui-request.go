@@ -15,9 +15,12 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/go-curses/cdk/log"
+	"github.com/go-curses/ctk"
+	"github.com/go-curses/ctk/lib/enums"
 
 	editor "github.com/go-curses/coreutils-etc-hosts-editor"
 )
@@ -30,6 +33,9 @@ func (c *CUI) requestReload() {
 		log.Error(c.LastError)
 		return
 	}
+	c.HostFile.Backups = c.Backups
+	c.History.Clear()
+	c.Marked, c.markAnchor = nil, nil
 	c.requestReloadContents()
 }
 
@@ -39,16 +45,48 @@ func (c *CUI) requestReloadContents() {
 }
 
 func (c *CUI) requestSave() {
+	if c.HostFile != nil {
+		diff, err := c.HostFile.Diff()
+		if err != nil {
+			c.newSaveFailedDialog(err)
+			return
+		} else if diff == "" {
+			log.DebugF("no changes to save to: %v", c.SourceFile)
+		} else {
+			c.newSaveDiffDialog(diff)
+			return
+		}
+	}
+	c.requestReload()
+	c.QuitButton.GrabFocus()
+}
+
+func (c *CUI) requestSaveConfirmed() {
 	log.DebugF("saving to: %v", c.SourceFile)
 	if c.HostFile != nil {
-		if err := c.HostFile.Save(); err != nil {
-			log.Error(err)
+		if err := c.HostFile.Save(); errors.Is(err, editor.ErrModified) {
+			c.newModifiedDialog()
+			return
+		} else if err != nil {
+			c.newSaveFailedDialog(err)
+			return
 		}
 	}
 	c.requestReload()
 	c.QuitButton.GrabFocus()
 }
 
+// newSaveFailedDialog reports the error saving, the changes are kept so the
+// problem can be fixed and the save tried again
+func (c *CUI) newSaveFailedDialog(err error) {
+	log.Error(err)
+	message := err.Error()
+	dialog := ctk.NewMessageDialog("Save Failed", message)
+	// the message is wrapped to the width of the dialog
+	dialog.SetSizeRequest(64, len(message)/50+7)
+	dialog.RunFunc(func(_ enums.ResponseType, _ ...interface{}) {})
+}
+
 func (c *CUI) requestQuit() {
 	c.Display.RequestQuit()
 }
@@ -0,0 +1,438 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/go-curses/cdk"
+	cenums "github.com/go-curses/cdk/lib/enums"
+	"github.com/go-curses/cdk/lib/ptypes"
+	"github.com/go-curses/ctk"
+
+	editor "github.com/go-curses/coreutils-etc-hosts-editor"
+)
+
+// gSidebarWheelStep is the number of rows scrolled by each mouse wheel
+// impulse
+const gSidebarWheelStep = 3
+
+// SidebarRow is one entry of a SidebarList
+type SidebarRow struct {
+	Key  string
+	Host *editor.Host
+}
+
+// sidebarRowView is what a button shows for a SidebarRow, used to skip
+// updating buttons which already show the same thing
+type sidebarRowView struct {
+	host    *editor.Host
+	text    string
+	name    string
+	tooltip string
+	active  bool
+	marked  bool
+}
+
+// SidebarList is a virtualized list of sidebar rows. Only the rows which fit
+// in the allocated height are materialized, as a pool of buttons which are
+// reused as the list scrolls and as the rows change
+type SidebarList struct {
+	ctk.HBox
+
+	name    string
+	c       *CUI
+	pool    ctk.VBox
+	scroll  ctk.VScrollbar
+	buttons []ctk.Button
+	views   []sidebarRowView
+	rows    []SidebarRow
+	offset  int
+	visible int
+}
+
+func (c *CUI) newSidebarList(name string) (l *SidebarList) {
+	l = &SidebarList{name: name, c: c}
+	l.HBox = ctk.NewHBox(false, 0)
+	l.HBox.Show()
+
+	l.pool = ctk.NewVBox(false, 0)
+	l.pool.Show()
+	l.pool.Connect(ctk.SignalResize, name+"-list-resize-handler", l.resize)
+	l.HBox.PackStart(l.pool, true, true, 0)
+
+	// the filler takes up the space below the last row, so the pool has a
+	// visible child when there are no rows and does not shrink to nothing
+	filler := ctk.NewLabel("")
+	filler.Show()
+	l.pool.PackEnd(filler, true, true, 0)
+
+	l.scroll = ctk.NewVScrollbar()
+	l.scroll.Connect(ctk.SignalValueChanged, name+"-list-scroll-handler", func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		l.ScrollTo(l.scroll.GetValue())
+		return cenums.EVENT_PASS
+	})
+	l.HBox.PackEnd(l.scroll, false, false, 0)
+	return
+}
+
+// resize updates the number of rows shown to fit the height of the list,
+// adding buttons to the pool as needed. This happens while the window is
+// being resized, so the buttons are added afterwards
+func (l *SidebarList) resize(data []interface{}, argv ...interface{}) cenums.EventFlag {
+	if height := l.pool.GetAllocation().H; height != l.visible {
+		_ = l.c.Display.AsyncCall(func(d cdk.Display) error {
+			l.setVisible(l.pool.GetAllocation().H)
+			d.RequestDraw()
+			d.RequestShow()
+			return nil
+		})
+	}
+	return cenums.EVENT_PASS
+}
+
+func (l *SidebarList) setVisible(height int) {
+	if height < 0 {
+		height = 0
+	}
+	for len(l.buttons) < height {
+		l.addButton()
+	}
+	l.visible = height
+	l.render()
+	l.pool.Resize()
+}
+
+func (l *SidebarList) addButton() {
+	slot := len(l.buttons)
+	label := ctk.NewLabel("")
+	label.Show()
+	label.SetJustify(cenums.JUSTIFY_LEFT)
+	label.SetSizeRequest(-1, 1)
+	label.SetSingleLineMode(true)
+
+	b := ctk.NewButtonWithWidget(label)
+	b.SetSizeRequest(gSidebarInnerWidth, 1)
+	b.SetHasTooltip(true)
+	b.Connect(ctk.SignalActivate, fmt.Sprintf("%v-list-row-%d-handler", l.name, slot), func(data []interface{}, argv ...interface{}) cenums.EventFlag {
+		if row, ok := l.rowAt(slot); ok {
+			l.c.Marked, l.c.markAnchor = nil, nil
+			l.c.focusEditor(row.Host)
+		}
+		return cenums.EVENT_STOP
+	})
+	l.pool.PackStart(b, false, false, 0)
+	l.buttons = append(l.buttons, b)
+	l.views = append(l.views, sidebarRowView{})
+}
+
+func (l *SidebarList) rowAt(slot int) (row SidebarRow, ok bool) {
+	if idx := l.offset + slot; slot < l.visible && idx < len(l.rows) {
+		return l.rows[idx], true
+	}
+	return
+}
+
+// SetRows replaces the rows of the list, only the buttons showing rows that
+// are different are updated
+func (l *SidebarList) SetRows(rows []SidebarRow) {
+	l.rows = rows
+	l.ScrollTo(l.offset)
+}
+
+// ScrollTo makes the row at offset the first one shown, within the limits
+// of the rows available
+func (l *SidebarList) ScrollTo(offset int) {
+	if last := len(l.rows) - l.visible; offset > last {
+		offset = last
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	l.offset = offset
+	l.render()
+}
+
+// ScrollToHost scrolls the list to show the first row of the host, if the
+// host is not already shown
+func (l *SidebarList) ScrollToHost(host *editor.Host) {
+	for idx, row := range l.rows {
+		if row.Host == host {
+			if idx < l.offset || idx >= l.offset+l.visible {
+				l.ScrollTo(idx - l.visible/2)
+			}
+			return
+		}
+	}
+}
+
+// render updates the buttons to show the rows from the current offset
+func (l *SidebarList) render() {
+	for slot, b := range l.buttons {
+		row, ok := l.rowAt(slot)
+		if !ok {
+			if l.views[slot].host != nil || b.IsVisible() {
+				l.views[slot] = sidebarRowView{}
+				b.Hide()
+			}
+			continue
+		}
+		view := l.c.sidebarRowView(row)
+		if view != l.views[slot] {
+			l.views[slot] = view
+			b.SetLabel(view.text)
+			b.SetName(view.name)
+			if view.active {
+				b.SetTheme(SidebarActiveTheme)
+			} else {
+				b.SetTheme(SidebarButtonTheme)
+			}
+			b.SetTooltipText(view.tooltip)
+		}
+		b.Show()
+	}
+
+	total := len(l.rows)
+	l.scroll.SetRange(0, max(total-l.visible, 0))
+	l.scroll.SetPageSize(l.visible)
+	l.scroll.SetIncrements(1, max(l.visible, 1))
+	if l.scroll.GetValue() != l.offset {
+		l.scroll.SetValue(l.offset)
+	}
+	if total > l.visible {
+		l.scroll.Show()
+	} else {
+		l.scroll.Hide()
+	}
+}
+
+// focusedSlot returns the slot of the button with the focus, or -1
+func (l *SidebarList) focusedSlot() int {
+	for slot, b := range l.buttons {
+		if b.HasFocus() {
+			return slot
+		}
+	}
+	return -1
+}
+
+// moveFocus moves the focus from the button at slot by delta rows,
+// scrolling when moving past the first or last row shown
+func (l *SidebarList) moveFocus(slot, delta int) {
+	if len(l.rows) == 0 {
+		return
+	}
+	target := min(max(l.offset+slot+delta, 0), len(l.rows)-1)
+	if target < l.offset {
+		l.ScrollTo(target)
+	} else if target >= l.offset+l.visible {
+		l.ScrollTo(target - l.visible + 1)
+	}
+	if slot = target - l.offset; slot >= 0 && slot < len(l.buttons) {
+		l.buttons[slot].GrabFocus()
+	}
+}
+
+// processKey handles moving through the rows with the cursor keys when one
+// of the buttons has the focus
+func (l *SidebarList) processKey(e *cdk.EventKey) cenums.EventFlag {
+	slot := l.focusedSlot()
+	if slot < 0 {
+		return cenums.EVENT_PASS
+	}
+	if e.Modifiers().Has(cdk.ModShift) {
+		return l.processShiftKey(e, slot)
+	}
+	switch e.Key() {
+	case cdk.KeyRune:
+		if e.Rune() != ' ' {
+			return cenums.EVENT_PASS
+		}
+		if row, ok := l.rowAt(slot); ok {
+			l.c.toggleMark(row.Host)
+		}
+	case cdk.KeyEsc:
+		if len(l.c.Marked) == 0 {
+			return cenums.EVENT_PASS
+		}
+		l.c.clearMarks()
+	case cdk.KeyUp:
+		l.moveFocus(slot, -1)
+	case cdk.KeyDown:
+		l.moveFocus(slot, 1)
+	case cdk.KeyPgUp:
+		l.moveFocus(slot, -l.visible)
+	case cdk.KeyPgDn:
+		l.moveFocus(slot, l.visible)
+	case cdk.KeyHome:
+		l.moveFocus(slot, -len(l.rows))
+	case cdk.KeyEnd:
+		l.moveFocus(slot, len(l.rows))
+	default:
+		return cenums.EVENT_PASS
+	}
+	return cenums.EVENT_STOP
+}
+
+// processShiftKey moves through the rows like processKey, marking all the
+// rows from the mark anchor to the row moved to
+func (l *SidebarList) processShiftKey(e *cdk.EventKey, slot int) cenums.EventFlag {
+	var delta int
+	switch e.Key() {
+	case cdk.KeyUp:
+		delta = -1
+	case cdk.KeyDown:
+		delta = 1
+	case cdk.KeyPgUp:
+		delta = -l.visible
+	case cdk.KeyPgDn:
+		delta = l.visible
+	case cdk.KeyHome:
+		delta = -len(l.rows)
+	case cdk.KeyEnd:
+		delta = len(l.rows)
+	default:
+		return cenums.EVENT_PASS
+	}
+	if len(l.rows) == 0 {
+		return cenums.EVENT_STOP
+	}
+	from := l.offset + slot
+	if l.c.markAnchor == nil || !l.c.isMarked(l.c.markAnchor) {
+		l.c.markAnchor = l.rows[from].Host
+	}
+	target := min(max(from+delta, 0), len(l.rows)-1)
+	l.c.markRange(l, target)
+	l.moveFocus(slot, delta)
+	return cenums.EVENT_STOP
+}
+
+// shown reports whether any rows of the list are on screen, the list itself
+// stays visible when the frame of its section is hidden
+func (l *SidebarList) shown() bool {
+	if parent := l.GetParent(); parent != nil && !parent.IsVisible() {
+		return false
+	}
+	return l.IsVisible() && l.visible > 0
+}
+
+// processMouse scrolls the list for mouse wheel events over it and marks
+// the rows from the mark anchor to the row shift-clicked
+func (l *SidebarList) processMouse(e *cdk.EventMouse) cenums.EventFlag {
+	point := ptypes.NewPoint2I(e.Position())
+	if !l.shown() || !l.HasPoint(point) {
+		return cenums.EVENT_PASS
+	}
+	if !e.IsWheelImpulse() {
+		if !e.Modifiers().Has(cdk.ModShift) || !e.IsPressed() {
+			return cenums.EVENT_PASS
+		}
+		for slot, b := range l.buttons {
+			if b.IsVisible() && b.HasPoint(point) {
+				l.c.markRange(l, l.offset+slot)
+				return cenums.EVENT_STOP
+			}
+		}
+		return cenums.EVENT_PASS
+	}
+	switch e.WheelImpulse() {
+	case cdk.WheelUp:
+		l.ScrollTo(l.offset - gSidebarWheelStep)
+	case cdk.WheelDown:
+		l.ScrollTo(l.offset + gSidebarWheelStep)
+	default:
+		return cenums.EVENT_PASS
+	}
+	return cenums.EVENT_STOP
+}
+
+// sidebarLists returns all the sidebar lists
+func (c *CUI) sidebarLists() []*SidebarList {
+	return []*SidebarList{
+		c.SidebarLocalsList,
+		c.SidebarCustomList,
+		c.SidebarBlockedList,
+		c.SidebarCommentsList,
+		c.SidebarEntryList,
+	}
+}
+
+// renderSidebarLists updates the rows shown by all the sidebar lists, after
+// the selection or the content of the hosts changed
+func (c *CUI) renderSidebarLists() {
+	for _, l := range c.sidebarLists() {
+		l.render()
+	}
+}
+
+// sidebarRowSelected reports whether the row is for the SelectedHost
+func (c *CUI) sidebarRowSelected(row SidebarRow) bool {
+	return row.Host == c.SelectedHost
+}
+
+func (c *CUI) sidebarRowView(row SidebarRow) (view sidebarRowView) {
+	host, key := row.Host, row.Key
+	findings := host.Findings()
+
+	view.host = host
+	view.text = key
+	if len(findings) > 0 {
+		switch findings.Worst() {
+		case editor.SeverityError:
+			view.text += gSidebarErrorBadge
+		case editor.SeverityWarning:
+			view.text += gSidebarWarningBadge
+		}
+	}
+
+	if view.marked = c.isMarked(host); view.marked {
+		view.text = gSidebarMarkedBadge + view.text
+	}
+
+	if c.sidebarRowSelected(row) {
+		view.name = "editing-list-selected"
+	} else {
+		view.name = "editing-list-unselected"
+	}
+
+	if view.active = host.Active(); view.active {
+		view.tooltip = key + " is active"
+	} else {
+		view.tooltip = key + " is inactive"
+	}
+
+	if group, found := editor.DefaultPolicy.Group(host.Importance()); found {
+		label := group.Label
+		if label == "" {
+			label = string(group.Name)
+		}
+		view.tooltip += "\n" + key + " is one of the " + label + " entries"
+	} else if host.IsBlocked() {
+		view.tooltip += "\n" + key + " is blocked"
+	} else if lookup := host.Lookup(); lookup == "" {
+		view.tooltip += "\n" + key + " points to a static ip address"
+	} else {
+		view.tooltip += "\n" + key + " points to a dynamic ip address"
+		view.tooltip += "\n" + key + " gets the ip from " + lookup
+	}
+	if host.IsProtected() {
+		view.tooltip += "\n" + key + " is protected by the policy"
+	}
+
+	for _, finding := range findings {
+		view.tooltip += fmt.Sprintf("\n%v: %v", finding.Severity, finding.Message)
+	}
+	return
+}
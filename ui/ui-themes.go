@@ -34,13 +34,19 @@ var (
 var ButtonActiveTheme paint.ThemeName = "toggle-button-active"
 
 func init() {
+	initThemes(paint.ColorNavy)
+}
+
+// initThemes registers the themes of the editor, with the background color
+// given behind the window and the sidebar
+func initThemes(background paint.Color) {
 	theme := paint.GetDefaultColorTheme()
 
 	borders, _ := paint.GetDefaultBorderRunes(paint.RoundedBorder)
 	arrows, _ := paint.GetArrows(paint.WideArrow)
 
 	style := paint.GetDefaultColorStyle()
-	style = style.Background(paint.ColorNavy)
+	style = style.Background(background)
 	styleLight := style.Foreground(paint.ColorWhite)
 
 	WindowTheme = theme.Clone()
@@ -93,9 +99,9 @@ func init() {
 	}
 	paint.RegisterTheme(ButtonActiveTheme, ActiveButtonTheme)
 
-	styleNormal = style.Foreground(paint.ColorWhite).Background(paint.ColorNavy)
-	styleActive = style.Foreground(paint.ColorWhite).Background(paint.ColorNavy)
-	styleInsensitive = style.Foreground(paint.ColorDarkSlateGray).Background(paint.ColorNavy)
+	styleNormal = style.Foreground(paint.ColorWhite).Background(background)
+	styleActive = style.Foreground(paint.ColorWhite).Background(background)
+	styleInsensitive = style.Foreground(paint.ColorDarkSlateGray).Background(background)
 	SidebarButtonTheme = paint.Theme{
 		Content: paint.ThemeAspect{
 			Normal:      styleNormal.Dim(true).Bold(true),
@@ -179,9 +185,9 @@ func init() {
 
 	SidebarFrameTheme = theme.Clone()
 
-	styleNormal = style.Foreground(paint.ColorWhite).Background(paint.ColorNavy)
-	styleActive = style.Foreground(paint.ColorWhite).Background(paint.ColorNavy)
-	styleInsensitive = style.Foreground(paint.ColorDarkSlateGray).Background(paint.ColorNavy)
+	styleNormal = style.Foreground(paint.ColorWhite).Background(background)
+	styleActive = style.Foreground(paint.ColorWhite).Background(background)
+	styleInsensitive = style.Foreground(paint.ColorDarkSlateGray).Background(background)
 	SidebarFrameTheme.Content = paint.ThemeAspect{
 		Normal:      styleNormal.Dim(true).Bold(false),
 		Selected:    styleActive.Dim(true).Bold(false),
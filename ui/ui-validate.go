@@ -0,0 +1,55 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"github.com/go-curses/cdk"
+)
+
+// requestValidate validates the hosts in the background, only those changed
+// since the last run when possible as validating all of them takes a while
+// for large hosts files, and then updates the sidebar rows with the findings.
+// Requests made while validating are coalesced into one more run
+func (c *CUI) requestValidate() {
+	c.Lock()
+	c.validateNext = c.HostFile
+	if c.validating {
+		c.Unlock()
+		return
+	}
+	c.validating = true
+	c.Unlock()
+
+	go func() {
+		for {
+			c.Lock()
+			hostfile := c.validateNext
+			c.validateNext = nil
+			if hostfile == nil {
+				c.validating = false
+				c.Unlock()
+				break
+			}
+			c.Unlock()
+			hostfile.ValidateChanged()
+		}
+		_ = c.Display.AsyncCall(func(d cdk.Display) error {
+			c.renderSidebarLists()
+			d.RequestDraw()
+			d.RequestShow()
+			return nil
+		})
+	}()
+}
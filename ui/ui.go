@@ -15,6 +15,7 @@
 package ui
 
 import (
+	"context"
 	_ "embed"
 
 	"github.com/go-curses/cdk"
@@ -38,13 +39,30 @@ const (
 	ListByEntry
 )
 
+// ParseSidebarListMode returns the mode for the name given, one of "domain",
+// "address" or "entry"
+func ParseSidebarListMode(name string) (mode SidebarListMode, ok bool) {
+	switch name {
+	case "domain":
+		return ListByDomain, true
+	case "address":
+		return ListByAddress, true
+	case "entry":
+		return ListByEntry, true
+	}
+	return ListByDomain, false
+}
+
 type CUI struct {
 	App ctk.Application
 
 	HostFile     *editor.Hostfile
+	ParseReport  editor.Diagnostics
 	SourceFile   string
 	LastError    error
 	ReadOnlyMode bool
+	Backups      int
+	Presets      []editor.Preset
 
 	ContentsHBox ctk.HBox
 	ActionHBox   ctk.HButtonBox
@@ -61,10 +79,12 @@ type CUI struct {
 	ByEntryButton   ctk.Button
 
 	SidebarFrame        ctk.Frame
-	SidebarEntryList    ctk.VBox
-	SidebarLocalsList   ctk.VBox
-	SidebarCustomList   ctk.VBox
-	SidebarCommentsList ctk.VBox
+	SidebarFilterEntry  ctk.Entry
+	SidebarEntryList    *SidebarList
+	SidebarLocalsList   *SidebarList
+	SidebarCustomList   *SidebarList
+	SidebarBlockedList  *SidebarList
+	SidebarCommentsList *SidebarList
 
 	SidebarAddEntryButton      ctk.Button
 	SidebarMoveEntryUpButton   ctk.Button
@@ -75,19 +95,27 @@ type CUI struct {
 	AddressEntry   ctk.Entry
 	AddressButton  ctk.Button
 	DomainsEntry   ctk.Entry
+	TrailingEntry  ctk.Entry
 	ActivateButton ctk.Button
 	DeleteButton   ctk.Button
 
 	HostSelectedFrame    ctk.Frame
 	NothingSelectedFrame ctk.Frame
 	CommentSelectedFrame ctk.Frame
-
-	SidebarMode  SidebarListMode
-	SelectedHost *editor.Host
-
-	EditorCommentList   []*editor.Host
-	EditorAddressLookup map[string]*editor.Host
-	EditorDomainsLookup map[string]*editor.Host
+	MarkedSelectedFrame  ctk.Frame
+	MarkedSummaryLabel   ctk.Label
+	MarkedAddressEntry   ctk.Entry
+
+	SidebarMode   SidebarListMode
+	SidebarFilter string
+	SelectedHost  *editor.Host
+	Marked        map[*editor.Host]bool
+	History       EditHistory
+
+	lookupCancel context.CancelFunc
+	markAnchor   *editor.Host
+	validating   bool
+	validateNext *editor.Hostfile
 
 	sync.RWMutex
 }
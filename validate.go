@@ -0,0 +1,429 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Finding is a problem found by a ValidationRule
+type Finding struct {
+	// Rule is the Name of the rule which reported the finding
+	Rule string
+	// Severity indicates how serious the problem is
+	Severity Severity
+	// Host is the entry with the problem, nil for problems with the file as
+	// a whole
+	Host *Host
+	// Line is the line number of Host within the content Save would write,
+	// zero when Host is nil
+	Line int
+	// Message is a short description of the problem
+	Message string
+}
+
+// String returns the finding in "line: severity: message (rule)" form
+func (f Finding) String() string {
+	return fmt.Sprintf("%d: %v: %v (%v)", f.Line, f.Severity, f.Message, f.Rule)
+}
+
+type Findings []Finding
+
+// Worst returns the highest Severity present, or SeverityInfo if there are
+// no findings
+func (f Findings) Worst() (worst Severity) {
+	for _, finding := range f {
+		if finding.Severity > worst {
+			worst = finding.Severity
+		}
+	}
+	return
+}
+
+// ValidationRule checks all the hosts of a Hostfile, in order, and reports
+// any findings. Check need not set the Rule or Line of its findings
+type ValidationRule struct {
+	Name        string
+	Description string
+	Check       func(hosts []*Host) (findings Findings)
+}
+
+// ValidationRules are run by Validate in the order given, add to these to
+// extend the validation
+var ValidationRules = []ValidationRule{
+	{"address", "host entries must have a valid IP address", checkAddresses},
+	{"syntax", "domains must not contain spaces or comment markers", checkSyntax},
+	{"hostname", "domains must be valid RFC 1123 hostnames", checkHostnames},
+	{"ip-literal", "domains must not be IP addresses", checkIPLiterals},
+	{"conflict", "a domain must not be mapped to different addresses", checkConflicts},
+	{"duplicate", "host entries must not be repeated", checkDuplicates},
+	{"empty", "host entries must have an address and domains", checkEmpty},
+	{"required", "the entries required by the policy must be present", checkRequired},
+}
+
+func newFinding(host *Host, severity Severity, message string, argv ...interface{}) Finding {
+	return Finding{Host: host, Severity: severity, Message: fmt.Sprintf(message, argv...)}
+}
+
+// Validate runs all ValidationRules and returns the findings ordered by line,
+// with problems of the file as a whole last. The findings for each host are
+// also available from Host.Findings until the next call to Validate
+func (eh *Hostfile) Validate() (findings Findings) {
+	// the index is needed to know which hosts are related to those changed
+	// after this, for ValidateChanged
+	eh.RLock()
+	eh.indexLock.Lock()
+	eh.getIndex()
+	eh.changed, eh.validated = make(map[*Host]bool), true
+	eh.indexLock.Unlock()
+	hosts := eh.hosts
+	eh.RUnlock()
+
+	spans := eh.lineSpans()
+
+	for _, rule := range ValidationRules {
+		for _, finding := range rule.Check(hosts) {
+			finding.Rule = rule.Name
+			if finding.Host != nil {
+				finding.Line = spans[finding.Host].number
+			}
+			findings = append(findings, finding)
+		}
+	}
+	sortFindings(findings)
+	attachFindings(hosts, findings)
+
+	eh.indexLock.Lock()
+	eh.lines = spans
+	eh.indexLock.Unlock()
+	return
+}
+
+// ValidateChanged is Validate for only the hosts whose findings may have
+// changed since the last Validate, which are those edited and those sharing
+// an address or domain with them before or after the edit, and returns their
+// findings. This relies on the ValidationRules only relating hosts with the
+// same address or domain, as the rules given do. The Line of the findings is
+// that found by the last Validate and problems of the file as a whole are
+// not reported. Everything is validated again when hosts were added, removed
+// or moved since the last Validate, or when the lines of hosts moved
+func (eh *Hostfile) ValidateChanged() (findings Findings) {
+	eh.indexLock.Lock()
+	validated, changed, spans := eh.validated, eh.changed, eh.lines
+	eh.changed = make(map[*Host]bool)
+	eh.indexLock.Unlock()
+	if !validated || spans == nil {
+		return eh.Validate()
+	}
+	for host := range changed {
+		if span, found := spans[host]; !found || span.count != len(host.Lines()) || span.blocked != host.IsBlocked() {
+			return eh.Validate()
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	// the findings of the changed hosts depend on the hosts sharing their
+	// addresses and domains, and on all the hosts with required domains
+	related := make(map[*Host]bool)
+	for host := range changed {
+		related[host] = true
+		if address := host.Address(); address != "" {
+			for _, other := range eh.HostsForAddress(address) {
+				related[other] = true
+			}
+		}
+		for _, domain := range hostDomains(host) {
+			for _, other := range eh.HostsForDomain(domain) {
+				related[other] = true
+			}
+		}
+	}
+	for _, required := range DefaultPolicy.Required {
+		for _, other := range eh.HostsForDomain(required.Domain) {
+			related[other] = true
+		}
+	}
+	var hosts, checked []*Host
+	for host := range related {
+		hosts = append(hosts, host)
+	}
+	eh.sortHosts(hosts)
+	for _, host := range hosts {
+		if changed[host] {
+			checked = append(checked, host)
+		}
+	}
+
+	for _, rule := range ValidationRules {
+		for _, finding := range rule.Check(hosts) {
+			if finding.Host != nil && changed[finding.Host] {
+				finding.Rule = rule.Name
+				finding.Line = spans[finding.Host].number
+				findings = append(findings, finding)
+			}
+		}
+	}
+	sortFindings(findings)
+	attachFindings(checked, findings)
+	return
+}
+
+// sortFindings orders the findings by line, with problems of the file as a
+// whole last
+func sortFindings(findings Findings) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		li, lj := findings[i].Line, findings[j].Line
+		if li == 0 || lj == 0 {
+			return li != 0 && lj == 0
+		}
+		return li < lj
+	})
+}
+
+// attachFindings replaces the findings of each of the hosts with those found
+func attachFindings(hosts []*Host, findings Findings) {
+	attached := make(map[*Host]Findings, len(hosts))
+	for _, finding := range findings {
+		if finding.Host != nil {
+			attached[finding.Host] = append(attached[finding.Host], finding)
+		}
+	}
+	for _, host := range hosts {
+		host.Lock()
+		host.findings = attached[host]
+		host.Unlock()
+	}
+}
+
+// activeSeverity is the severity of a problem which breaks name resolution
+// when the host is active, and is only a warning when it is not
+func activeSeverity(host *Host) Severity {
+	if host.Active() {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// hostDomains returns the domains of the host without any empty strings left
+// by editing
+func hostDomains(host *Host) (domains []string) {
+	for _, domain := range host.Domains() {
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return
+}
+
+func checkAddresses(hosts []*Host) (findings Findings) {
+	for _, host := range hosts {
+		if host.IsOnlyComment() {
+			continue
+		}
+		if address := host.Address(); address != "" && net.ParseIP(address) == nil {
+			findings = append(findings, newFinding(host, activeSeverity(host), "%q is not a valid IP address", address))
+		}
+	}
+	return
+}
+
+// checkSyntax reports domains which would not be read back as written, as
+// more than one domain or as a comment
+func checkSyntax(hosts []*Host) (findings Findings) {
+	for _, host := range hosts {
+		if host.IsOnlyComment() {
+			continue
+		}
+		for _, domain := range hostDomains(host) {
+			if strings.ContainsAny(domain, " \t\r\n#") {
+				findings = append(findings, newFinding(host, SeverityError, "%q cannot be written as a domain name", domain))
+			}
+		}
+		if lookup := host.Lookup(); strings.ContainsAny(lookup, " \t\r\n#") {
+			findings = append(findings, newFinding(host, SeverityError, "%q cannot be written as a lookup domain", lookup))
+		}
+	}
+	return
+}
+
+// validHostnameLabel reports whether the label is made of letters, digits and
+// hyphens, not starting or ending with a hyphen. This is checked for every
+// domain of every host, so it avoids the cost of a regular expression
+func validHostnameLabel(label string) bool {
+	last := len(label) - 1
+	for idx := 0; idx <= last; idx++ {
+		switch c := label[idx]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' && idx > 0 && idx < last:
+		default:
+			return false
+		}
+	}
+	return last >= 0
+}
+
+// validHostname reports whether name is a valid RFC 1123 hostname, allowing
+// for a trailing dot
+func validHostname(name string) (reason string, valid bool) {
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return "is longer than 253 characters", false
+	}
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return "has an empty label", false
+		case len(label) > 63:
+			return fmt.Sprintf("has a label longer than 63 characters: %q", label), false
+		case !validHostnameLabel(label):
+			return fmt.Sprintf("has an invalid label: %q", label), false
+		}
+	}
+	return "", true
+}
+
+func checkHostnames(hosts []*Host) (findings Findings) {
+	for _, host := range hosts {
+		if host.IsOnlyComment() {
+			continue
+		}
+		for _, domain := range hostDomains(host) {
+			if net.ParseIP(domain) != nil {
+				continue // see checkIPLiterals
+			}
+			if reason, valid := validHostname(domain); !valid {
+				findings = append(findings, newFinding(host, SeverityWarning, "%q is not a valid hostname, it %v", domain, reason))
+			}
+		}
+	}
+	return
+}
+
+func checkIPLiterals(hosts []*Host) (findings Findings) {
+	for _, host := range hosts {
+		if host.IsOnlyComment() {
+			continue
+		}
+		for _, domain := range hostDomains(host) {
+			if net.ParseIP(domain) != nil {
+				findings = append(findings, newFinding(host, SeverityWarning, "%q is an IP address, not a domain name", domain))
+			}
+		}
+	}
+	return
+}
+
+// checkConflicts reports active entries mapping a domain to a different
+// address of the same family as an earlier active entry, which is ignored
+// by the resolver
+func checkConflicts(hosts []*Host) (findings Findings) {
+	type mapping struct {
+		address string
+		ipv6    bool
+	}
+	seen := make(map[string][]mapping)
+	for _, host := range hosts {
+		if host.IsOnlyComment() || !host.Active() {
+			continue
+		}
+		ip := net.ParseIP(host.Address())
+		if ip == nil {
+			continue
+		}
+		ipv6 := ip.To4() == nil
+		for _, domain := range hostDomains(host) {
+			domain = strings.ToLower(domain)
+			var conflict string
+			for _, other := range seen[domain] {
+				if other.ipv6 == ipv6 && other.address != ip.String() {
+					conflict = other.address
+					break
+				}
+			}
+			if conflict != "" {
+				findings = append(findings, newFinding(host, SeverityWarning, "%v is already mapped to %v", domain, conflict))
+				continue
+			}
+			seen[domain] = append(seen[domain], mapping{address: ip.String(), ipv6: ipv6})
+		}
+	}
+	return
+}
+
+func checkDuplicates(hosts []*Host) (findings Findings) {
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		if host.IsOnlyComment() || host.Address() == "" {
+			continue
+		}
+		key := fmt.Sprintf("%v %v %v", host.Active(), host.Address(), strings.Join(hostDomains(host), " "))
+		if seen[key] {
+			findings = append(findings, newFinding(host, SeverityWarning, "duplicate of an earlier entry"))
+			continue
+		}
+		seen[key] = true
+	}
+	return
+}
+
+func checkEmpty(hosts []*Host) (findings Findings) {
+	for _, host := range hosts {
+		if host.IsOnlyComment() {
+			if strings.TrimSpace(host.Comment()) == "" {
+				findings = append(findings, newFinding(host, SeverityInfo, "empty comment entry"))
+			}
+			continue
+		}
+		address, domains := host.Address(), hostDomains(host)
+		switch {
+		case address == "" && len(domains) == 0:
+			findings = append(findings, newFinding(host, activeSeverity(host), "empty host entry"))
+		case address == "":
+			findings = append(findings, newFinding(host, activeSeverity(host), "host entry has no address"))
+		case len(domains) == 0:
+			findings = append(findings, newFinding(host, activeSeverity(host), "host entry has no domains"))
+		}
+	}
+	return
+}
+
+func checkRequired(hosts []*Host) (findings Findings) {
+	for _, required := range DefaultPolicy.Required {
+		var first *Host
+		var matched bool
+		for _, host := range hosts {
+			if host.IsOnlyComment() || !host.HasDomain(required.Domain) {
+				continue
+			} else if first == nil {
+				first = host
+			}
+			if matched = required.matches(host.Address()); matched {
+				break
+			}
+		}
+		switch {
+		case first == nil:
+			findings = append(findings, newFinding(nil, SeverityWarning, "missing required entry for %v", required.describe()))
+		case !matched:
+			findings = append(findings, newFinding(first, SeverityWarning, "required entry for %v should have %v", required.describe(), required.expected()))
+		}
+	}
+	return
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2023  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"testing"
+)
+
+func TestValidationRules(t *testing.T) {
+	const required = "127.0.0.1 localhost\n::1 ip6-localhost ip6-loopback\nff02::1 ip6-allnodes\nff02::2 ip6-allrouters\n"
+	for _, test := range []struct {
+		name    string
+		content string
+		edit    func(eh *Hostfile)
+		want    string
+	}{
+		{"valid", required + "10.0.0.1 router.lan nas.lan.\n", nil, ""},
+		{"invalid address", required + "10.0.0 a.lan\n#10.0.0 b.lan\n", nil,
+			"5: error: \"10.0.0\" is not a valid IP address (address)\n" +
+				"6: warning: \"10.0.0\" is not a valid IP address (address)\n"},
+		{"unwritable domain", required + "10.0.0.1 a.lan\n", func(eh *Hostfile) {
+			hostWithDomain(t, eh, "a.lan").SetDomains("a.lan b#lan")
+		}, "5: error: \"b#lan\" cannot be written as a domain name (syntax)\n" +
+			"5: warning: \"b#lan\" is not a valid hostname, it has an invalid label: \"b#lan\" (hostname)\n"},
+		{"invalid hostname", required + "10.0.0.1 -bad.lan under_score a..b ok.lan\n", nil,
+			"5: warning: \"-bad.lan\" is not a valid hostname, it has an invalid label: \"-bad\" (hostname)\n" +
+				"5: warning: \"under_score\" is not a valid hostname, it has an invalid label: \"under_score\" (hostname)\n" +
+				"5: warning: \"a..b\" is not a valid hostname, it has an empty label (hostname)\n"},
+		{"ip literal", required + "10.0.0.1 10.0.0.2\n", nil,
+			"5: warning: \"10.0.0.2\" is an IP address, not a domain name (ip-literal)\n"},
+		{"conflicting address", required + "10.0.0.1 a.lan\n::2 a.lan\n#10.0.0.3 a.lan\n10.0.0.2 A.lan\n10.0.0.1 a.lan b.lan\n", nil,
+			"8: warning: a.lan is already mapped to 10.0.0.1 (conflict)\n"},
+		{"duplicate entry", required + "10.0.0.1 a.lan b.lan\n#10.0.0.1 a.lan b.lan\n10.0.0.1 a.lan b.lan\n10.0.0.1 b.lan a.lan\n", nil,
+			"7: warning: duplicate of an earlier entry (duplicate)\n"},
+		{"no domains", required + "10.0.0.1 a.lan\n#10.0.0.2 b.lan\n", func(eh *Hostfile) {
+			hostWithDomain(t, eh, "a.lan").SetDomains("")
+			hostWithDomain(t, eh, "b.lan").SetDomains("")
+		}, "5: error: host entry has no domains (empty)\n" +
+			"6: warning: host entry has no domains (empty)\n"},
+		{"no address", required + "10.0.0.1 a.lan\n", func(eh *Hostfile) {
+			hostWithDomain(t, eh, "a.lan").SetAddress("")
+		}, "5: error: host entry has no address (empty)\n"},
+		{"required address", "127.0.0.2 localhost\n::1 ip6-localhost\nff02::1 ip6-allnodes\nff02::2 ip6-allrouters\n", nil,
+			"1: warning: required entry for IPv4 (localhost) should have 127.0.0.1 (required)\n" +
+				"0: warning: missing required entry for IPv6 (loopback) (required)\n"},
+		{"required address later", "127.0.0.2 localhost\n" + required, nil,
+			"2: warning: localhost is already mapped to 127.0.0.2 (conflict)\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, test.content)
+			if test.edit != nil {
+				test.edit(eh)
+			}
+			if got := findingsText(eh.Validate()); got != test.want {
+				t.Errorf("Validate() =\n%v\nwant\n%v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateChanged(t *testing.T) {
+	content := "127.0.0.1 localhost\n::1 localhost\n1.1.1.1 a b\n2.2.2.2 c\n3.3.3.3 d\n3.3.3.3 d\n"
+	for _, test := range []struct {
+		name   string
+		domain string
+		edit   func(host *Host)
+	}{
+		{"conflict added", "c", func(host *Host) { host.SetDomains("c a") }},
+		{"conflict removed", "b", func(host *Host) { host.SetDomains("e") }},
+		{"duplicate removed", "d", func(host *Host) { host.SetAddress("4.4.4.4") }},
+		{"invalid address", "c", func(host *Host) { host.SetAddress("2.2.2") }},
+		{"invalid domain", "c", func(host *Host) { host.SetDomains("c bad_name") }},
+		{"deactivated", "a", func(host *Host) { host.SetActive(false) }},
+		{"required", "localhost", func(host *Host) { host.SetDomains("local") }},
+		{"invalid lookup", "c", func(host *Host) { host.SetLookup("a#b") }},
+		{"lookup policy", "c", func(host *Host) { host.SetLookup("example.com"); host.SetLookupPolicy("nearest") }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, content)
+			eh.Validate()
+			test.edit(hostWithDomain(t, eh, test.domain))
+			eh.ValidateChanged()
+			changed := make(map[*Host]string)
+			for _, host := range eh.Hosts() {
+				changed[host] = findingsText(host.Findings())
+			}
+			eh.Validate()
+			for idx, host := range eh.Hosts() {
+				if want := findingsText(host.Findings()); changed[host] != want {
+					t.Errorf("host %d findings = %q, want %q", idx, changed[host], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateChangedFindings(t *testing.T) {
+	const content = "127.0.0.1 localhost\n::1 ip6-localhost ip6-loopback\nff02::1 ip6-allnodes\nff02::2 ip6-allrouters\n" +
+		"10.0.0.1 a.lan\n10.0.0.2 b.lan\n10.0.0.3 c.lan\n10.0.0.3 c.lan\n"
+	for _, test := range []struct {
+		name string
+		edit func(eh *Hostfile)
+		want string
+	}{
+		{"unchanged", func(eh *Hostfile) {}, ""},
+		{"conflict added", func(eh *Hostfile) { hostWithDomain(t, eh, "b.lan").SetDomains("b.lan a.lan") },
+			"6: warning: a.lan is already mapped to 10.0.0.1 (conflict)\n"},
+		{"invalid domain", func(eh *Hostfile) { hostWithDomain(t, eh, "a.lan").SetDomains("a.lan bad_name") },
+			"5: warning: \"bad_name\" is not a valid hostname, it has an invalid label: \"bad_name\" (hostname)\n"},
+		{"duplicate removed", func(eh *Hostfile) { eh.Hosts()[7].SetDomains("d.lan") }, ""},
+		{"required changed", func(eh *Hostfile) { hostWithDomain(t, eh, "localhost").SetAddress("127.0.0.2") },
+			"1: warning: required entry for IPv4 (localhost) should have 127.0.0.1 (required)\n"},
+		{"host added", func(eh *Hostfile) { eh.InsertHost(NewHost("10.0.0.4", "c.lan"), -1) },
+			"8: warning: duplicate of an earlier entry (duplicate)\n" +
+				"10: warning: c.lan is already mapped to 10.0.0.3 (conflict)\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			eh := mustParse(t, content)
+			eh.Validate()
+			test.edit(eh)
+			if got := findingsText(eh.ValidateChanged()); got != test.want {
+				t.Errorf("ValidateChanged() =\n%v\nwant\n%v", got, test.want)
+			}
+			if got := findingsText(eh.ValidateChanged()); got != "" {
+				t.Errorf("ValidateChanged() again =\n%v\nwant nothing", got)
+			}
+		})
+	}
+}
+
+func TestValidateChangedAfterRemoving(t *testing.T) {
+	eh := mustParse(t, "1.1.1.1 a\n2.2.2.2 a\n")
+	eh.Validate()
+	second := eh.Hosts()[1]
+	if len(second.Findings()) == 0 {
+		t.Fatalf("expected a conflict for the second host")
+	}
+	eh.RemoveHost(eh.Hosts()[0])
+	eh.ValidateChanged()
+	if findings := second.Findings(); len(findings) != 0 {
+		t.Errorf("findings after removing the conflicting host: %v", findings)
+	}
+}
+
+func findingsText(findings Findings) (text string) {
+	for _, finding := range findings {
+		text += finding.String() + "\n"
+	}
+	return
+}